@@ -0,0 +1,283 @@
+package hermes
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Whoever made the request, as established by an Authenticator
+type Principal struct {
+	Subject string
+	Scheme  string
+	Claims  map[string]interface{}
+}
+
+// Authenticators return ErrNoCredentials when the request has no
+// credentials they understand, so that the next one gets a chance
+type Authenticator interface {
+	Authenticate(req *http.Request) (*Principal, error)
+}
+
+type AuthenticatorFunc func(req *http.Request) (*Principal, error)
+
+func (fn AuthenticatorFunc) Authenticate(req *http.Request) (*Principal, error) {
+	return fn(req)
+}
+
+var ErrNoCredentials = errors.New("No credentials")
+
+// The Router makes the principal available in the context of requests
+const principalKey = "Hermes-Principal"
+
+// Returns the principal of the request, nil for public endpoints
+func PrincipalFrom(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey).(*Principal)
+	return principal
+}
+
+// Verifies the signature of the request first if the router requires
+// them, then tries its authenticators. The principal of the signature is
+// only used when there are no authenticators
+func (router *Router) authenticate(req *http.Request) (*Principal, error) {
+	var principal *Principal
+	if router.Signatures != nil {
+		signed, err := router.Signatures.Verify(req)
+		if err != nil {
+			if typed := (&Error{}); !errors.As(err, &typed) {
+				err = Unauthenticated("%v", err)
+			}
+			return nil, err
+		}
+		principal = signed
+	}
+	if len(router.Authenticators) != 0 {
+		return authenticate(router.Authenticators, req)
+	}
+	return principal, nil
+}
+
+// Tries the authenticators in order until one of them finds credentials
+func authenticate(authenticators []Authenticator, req *http.Request) (*Principal, error) {
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(req)
+		if err == ErrNoCredentials {
+			continue
+		} else if err != nil {
+			if typed := (&Error{}); !errors.As(err, &typed) {
+				err = Unauthenticated("%v", err)
+			}
+			return nil, err
+		}
+		if principal == nil {
+			return nil, Unauthenticated("Invalid credentials")
+		}
+		return principal, nil
+	}
+	return nil, Unauthenticated("Missing credentials")
+}
+
+// Authenticates requests with an "Authorization: Bearer <token>" header
+func BearerAuth(verify func(token string) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) (*Principal, error) {
+		token, found := bearerToken(req)
+		if !found {
+			return nil, ErrNoCredentials
+		}
+		return verify(token)
+	})
+}
+
+func bearerToken(req *http.Request) (string, bool) {
+	authorization := req.Header.Get("Authorization")
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(authorization[7:]), true
+}
+
+func BasicAuth(verify func(user, password string) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) (*Principal, error) {
+		user, password, found := req.BasicAuth()
+		if !found {
+			return nil, ErrNoCredentials
+		}
+		return verify(user, password)
+	})
+}
+
+func APIKeyAuth(header string, verify func(key string) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) (*Principal, error) {
+		key := req.Header.Get(header)
+		if key == "" {
+			return nil, ErrNoCredentials
+		}
+		return verify(key)
+	})
+}
+
+// The keys that JWTs can be signed with, by key id. Tokens without a key
+// id use the key of the empty id. Keys are []byte for HMAC, *rsa.PublicKey
+// for RSA and *ecdsa.PublicKey for ECDSA signatures
+type JWTKeys map[string]interface{}
+
+// Authenticates bearer JWTs signed with one of the keys. The subject of the
+// principal is the "sub" claim. Tokens must have an "exp" claim, and
+// expired tokens are rejected
+func JWTAuth(keys JWTKeys) Authenticator {
+	return jwtAuth(keys, true)
+}
+
+// Same as JWTAuth, but also accepts the tokens without an "exp" claim,
+// which never expire
+func LongLivedJWTAuth(keys JWTKeys) Authenticator {
+	return jwtAuth(keys, false)
+}
+
+func jwtAuth(keys JWTKeys, requireExpiry bool) Authenticator {
+	return BearerAuth(func(token string) (*Principal, error) {
+		claims, err := keys.verify(token, requireExpiry)
+		if err != nil {
+			return nil, err
+		}
+		subject, _ := claims["sub"].(string)
+		return &Principal{Subject: subject, Scheme: "Bearer", Claims: claims}, nil
+	})
+}
+
+var jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// Checks the signature and the validity period of the token, and returns
+// its claims. Tokens without an "exp" claim are rejected
+func (keys JWTKeys) Verify(token string) (map[string]interface{}, error) {
+	return keys.verify(token, true)
+}
+
+func (keys JWTKeys) verify(token string, requireExpiry bool) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, Unauthenticated("Malformed token")
+	}
+
+	header := struct{ Alg, Kid string }{}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	key, found := keys[header.Kid]
+	if !found {
+		return nil, Unauthenticated("Unknown token key '%s'", header.Kid)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, Unauthenticated("Malformed token signature")
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := float64(time.Now().Unix())
+	exp, ok := claims["exp"].(float64)
+	if !ok && requireExpiry {
+		return nil, Unauthenticated("Token does not expire")
+	} else if ok && now >= exp {
+		return nil, Unauthenticated("Token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return nil, Unauthenticated("Token not valid yet")
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	content, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return Unauthenticated("Malformed token")
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return Unauthenticated("Malformed token")
+	}
+	return nil
+}
+
+// The algorithm must match the type of the key, so that a public key cannot
+// be used as an HMAC secret
+func verifyJWTSignature(alg string, key interface{}, signed string, signature []byte) error {
+	if len(alg) != 5 {
+		return Unauthenticated("Unsupported token algorithm '%s'", alg)
+	}
+	hash, found := jwtHashes[alg[2:]]
+	if !found {
+		return Unauthenticated("Unsupported token algorithm '%s'", alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	valid := false
+	switch key := key.(type) {
+	case []byte:
+		if alg[:2] == "HS" {
+			mac := hmac.New(hash.New, key)
+			mac.Write([]byte(signed))
+			valid = hmac.Equal(mac.Sum(nil), signature)
+		}
+	case *rsa.PublicKey:
+		if alg[:2] == "RS" {
+			valid = rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if alg[:2] == "ES" && len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(key, digest, r, s)
+		}
+	}
+	if !valid {
+		return Unauthenticated("Invalid token signature")
+	}
+	return nil
+}
+
+// Attaches the credentials for the service with the given SNI to requests
+type Credentials func(ctx context.Context, sni string, req *http.Request) error
+
+// Sends the bearer token of each SNI
+func BearerCredentials(tokens map[string]string) Credentials {
+	return func(ctx context.Context, sni string, req *http.Request) error {
+		if token, found := tokens[sni]; found {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return nil
+	}
+}
+
+// Sends the key of each SNI in the header
+func APIKeyCredentials(header string, keys map[string]string) Credentials {
+	return func(ctx context.Context, sni string, req *http.Request) error {
+		if key, found := keys[sni]; found {
+			req.Header.Set(header, key)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,194 @@
+package hermes_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type GuardedService struct {
+	hermes.HealthChecker
+}
+
+func (_ GuardedService) SNI() string { return "guarded" }
+
+func (_ GuardedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("WhoAmI", "GET", "/whoami", nil, Inbound{}),
+		hermes.EP("Hello", "GET", "/hello", nil, Inbound{}).Public(),
+		hermes.Healthz,
+	}
+}
+
+func (_ GuardedService) WhoAmI(ctx context.Context) (*Inbound, error) {
+	return &Inbound{hermes.PrincipalFrom(ctx).Subject}, nil
+}
+
+func (_ GuardedService) Hello(ctx context.Context) (*Inbound, error) {
+	return &Inbound{"hello"}, nil
+}
+
+func signJWT(alg, kid string, claims map[string]interface{}, sign func(signed []byte) []byte) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func TestAuthentication(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	router := hermes.NewRouter(GuardedService{}).Authenticate(
+		hermes.JWTAuth(hermes.JWTKeys{"hmac": secret, "rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}),
+		hermes.BasicAuth(func(user, password string) (*hermes.Principal, error) {
+			if password != "hunter2" {
+				return nil, errors.New("Wrong password")
+			}
+			return &hermes.Principal{Subject: user, Scheme: "Basic"}, nil
+		}),
+	)
+	engine := gin.New()
+	require.NoError(t, router.Serve(engine))
+
+	hs256 := func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+	rs256 := func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		return signature
+	}
+	es256 := func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature
+	}
+	valid := map[string]interface{}{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+	expired := map[string]interface{}{"sub": "alice", "exp": time.Now().Add(-time.Hour).Unix()}
+	eternal := map[string]interface{}{"sub": "alice"}
+
+	call := func(authorize func(req *http.Request)) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/whoami", nil)
+		authorize(req)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	bearer := func(token string) func(req *http.Request) {
+		return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	for _, token := range []string{
+		signJWT("HS256", "hmac", valid, hs256),
+		signJWT("RS256", "rsa", valid, rs256),
+		signJWT("ES256", "ec", valid, es256),
+	} {
+		w := call(bearer(token))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"Message": "alice"}`, w.Body.String())
+	}
+
+	for _, authorize := range []func(req *http.Request){
+		func(req *http.Request) {},
+		bearer(signJWT("HS256", "hmac", expired, hs256)),
+		bearer(signJWT("HS256", "hmac", eternal, hs256)),
+		bearer(signJWT("HS256", "rsa", valid, hs256)), // Algorithm confusion
+		bearer(signJWT("HS256", "unknown", valid, hs256)),
+		bearer("not.a.token"),
+		func(req *http.Request) { req.SetBasicAuth("bob", "password") },
+	} {
+		w := call(authorize)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), hermes.CodeUnauthenticated)
+	}
+
+	w := call(func(req *http.Request) { req.SetBasicAuth("bob", "hunter2") })
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Message": "bob"}`, w.Body.String())
+
+	// Public endpoints skip authentication
+	for _, path := range []string{"/hello", "/hermes/healthz"} {
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// Callers attach the credentials of the service they call
+	caller := hermes.NewCaller(GuardedService{})
+	caller.Client = &hermes.MockClient{engine}
+	out := &Inbound{}
+	code, err := caller.Call(context.Background(), "WhoAmI", nil, out)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.True(t, errors.Is(err, hermes.Unauthenticated("")))
+
+	caller.Credentials = hermes.BearerCredentials(map[string]string{"guarded": signJWT("HS256", "hmac", valid, hs256)})
+	code, err = caller.Call(context.Background(), "WhoAmI", nil, out)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "alice", out.Message)
+}
+
+func TestLongLivedJWTAuth(t *testing.T) {
+	secret := []byte("secret")
+	hs256 := func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+	eternal := signJWT("HS256", "", map[string]interface{}{"sub": "alice"}, hs256)
+	expired := signJWT("HS256", "", map[string]interface{}{"sub": "alice", "exp": time.Now().Add(-time.Hour).Unix()}, hs256)
+
+	_, err := hermes.JWTKeys{"": secret}.Verify(eternal)
+	assert.EqualError(t, err, "Token does not expire")
+
+	req := httptest.NewRequest("GET", "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+eternal)
+	principal, err := hermes.LongLivedJWTAuth(hermes.JWTKeys{"": secret}).Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Subject)
+
+	req.Header.Set("Authorization", "Bearer "+expired)
+	_, err = hermes.LongLivedJWTAuth(hermes.JWTKeys{"": secret}).Authenticate(req)
+	assert.EqualError(t, err, "Token expired")
+}
+
+func TestNilPrincipal(t *testing.T) {
+	router := hermes.NewRouter(GuardedService{}).Authenticate(
+		hermes.APIKeyAuth("X-Key", func(key string) (*hermes.Principal, error) { return nil, nil }),
+	)
+	engine := gin.New()
+	require.NoError(t, router.Serve(engine))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/whoami", nil)
+	req.Header.Set("X-Key", "anything")
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid credentials")
+}
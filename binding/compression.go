@@ -0,0 +1,41 @@
+package binding
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Returns a reader of the content decoded with the content encoding, which
+// can be identity, gzip or deflate
+func Decompress(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return ioutil.NopCloser(r), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		return zlib.NewReader(r)
+	}
+	return nil, fmt.Errorf("Unsupported content encoding: %s", encoding)
+}
+
+// Replaces the body of a compressed request with its decoded content
+func DecompressRequest(req *http.Request) error {
+	encoding := req.Header.Get("Content-Encoding")
+	if encoding == "" {
+		return nil
+	}
+	body, err := Decompress(encoding, req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	req.ContentLength = -1
+	req.Header.Del("Content-Encoding")
+	return nil
+}
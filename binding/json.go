@@ -14,6 +14,9 @@ type JSONBinding struct{}
 
 func (_ *JSONBinding) Bind(ctx *gin.Context, obj interface{}) error {
 	if ctx.Request != nil && ctx.Request.ContentLength > 0 {
+		if err := DecompressRequest(ctx.Request); err != nil {
+			return err
+		}
 		return binding.JSON.Bind(ctx.Request, obj)
 	}
 	return nil
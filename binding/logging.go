@@ -0,0 +1,46 @@
+package binding
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/glog"
+)
+
+// Hermes routers put the logger of the request in its context under that
+// key
+const LoggerKey = "Hermes-Logger"
+
+type warner interface {
+	Warn(msg string, args ...interface{})
+}
+
+// Logs with the logger of the request, or to glog when bindings are used
+// without a router
+func warn(ctx *gin.Context, msg string, args ...interface{}) {
+	if logger, ok := ctx.Value(LoggerKey).(warner); ok && logger != nil {
+		logger.Warn(msg, args...)
+		return
+	}
+	glog.Warning(FormatLogEntry(msg, args))
+}
+
+// Formats log entries as the message followed by key=value pairs, quoting
+// the values that contain spaces, quotes, equal signs or newlines
+func FormatLogEntry(msg string, args []interface{}) string {
+	entry := &strings.Builder{}
+	entry.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			fmt.Fprintf(entry, " !BADKEY=%v", args[i])
+			break
+		}
+		value := fmt.Sprint(args[i+1])
+		if strings.ContainsAny(value, " \"=\n") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(entry, " %v=%s", args[i], value)
+	}
+	return entry.String()
+}
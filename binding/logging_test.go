@@ -0,0 +1,14 @@
+package binding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLogEntry(t *testing.T) {
+	assert.Equal(t, "Ignoring extra query values query=name values=2",
+		FormatLogEntry("Ignoring extra query values", []interface{}{"query", "name", "values", 2}))
+	assert.Equal(t, `Entry a="two words" b="x=y" !BADKEY=c`,
+		FormatLogEntry("Entry", []interface{}{"a", "two words", "b", "x=y", "c"}))
+}
@@ -0,0 +1,200 @@
+package binding
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Generates JSON Schemas from Go types, following the rules of
+// encoding/json. Named struct types are stored in Definitions and
+// referenced, which also handles recursive types.
+// Pointers, slices and maps can be null; pointers and fields with
+// omitempty are optional. The directives of hermes struct tags are listed
+// under "x-hermes", and those fields are optional since they are not bound
+// from the body
+type SchemaGenerator struct {
+	// Prefix of the references to definitions, e.g "#/definitions/"
+	RefPrefix string
+
+	// Marks nullable schemas with "nullable: true" like OpenAPI 3.0 does,
+	// instead of allowing the "null" type
+	Nullable bool
+
+	Definitions map[string]interface{}
+}
+
+func NewSchemaGenerator(refprefix string) *SchemaGenerator {
+	return &SchemaGenerator{RefPrefix: refprefix, Definitions: map[string]interface{}{}}
+}
+
+// Returns a standalone JSON Schema document for the type
+func JSONSchema(t reflect.Type) ([]byte, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	generator := NewSchemaGenerator("#/definitions/")
+	schema := generator.Schema(t)
+
+	// Inline the definition of the root type
+	if ref, isRef := schema["$ref"].(string); isRef {
+		root := map[string]interface{}{}
+		for key, value := range generator.Definitions[strings.TrimPrefix(ref, generator.RefPrefix)].(map[string]interface{}) {
+			root[key] = value
+		}
+		schema = root
+	}
+	schema["$schema"] = JSONSchemaDraft
+	if t.Name() != "" {
+		schema["title"] = t.Name()
+	}
+	if len(generator.Definitions) != 0 {
+		schema["definitions"] = generator.Definitions
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *SchemaGenerator) Schema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.nullable(g.Schema(t.Elem()))
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return g.nullable(map[string]interface{}{"type": "string", "format": "byte"})
+		}
+		return g.nullable(map[string]interface{}{"type": "array", "items": g.Schema(t.Elem())})
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.Schema(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		return g.nullable(map[string]interface{}{"type": "object", "additionalProperties": g.Schema(t.Elem())})
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return g.StructSchema(t)
+		}
+		name := DefinitionName(t)
+		if _, found := g.Definitions[name]; !found {
+			g.Definitions[name] = nil // Placeholder for recursive types
+			g.Definitions[name] = g.StructSchema(t)
+		}
+		return map[string]interface{}{"$ref": g.RefPrefix + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *SchemaGenerator) nullable(schema map[string]interface{}) map[string]interface{} {
+	_, isRef := schema["$ref"]
+	switch {
+	case g.Nullable && isRef:
+		return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+	case g.Nullable:
+		schema["nullable"] = true
+	case isRef:
+		return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+	default:
+		if typename, ok := schema["type"].(string); ok {
+			schema["type"] = []interface{}{typename, "null"}
+		}
+	}
+	return schema
+}
+
+// Returns the schema of the struct, with its fields inlined
+func (g *SchemaGenerator) StructSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []interface{}{}
+	for _, field := range JSONFields(t) {
+		name, omitempty := JSONName(field)
+		if name == "-" {
+			continue
+		}
+
+		schema := g.Schema(field.Type)
+		if directives := FieldDirectives(field); len(directives) != 0 {
+			schema["x-hermes"] = directives
+		} else if !omitempty && field.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+		properties[name] = schema
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) != 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Definition names only contain letters, digits, dots, dashes and
+// underscores, so that they can be used in OpenAPI documents as well
+func DefinitionName(t reflect.Type) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, t.String())
+}
+
+// Returns the exported fields of a struct as encoding/json sees them, with
+// the fields of embedded structs flattened
+func JSONFields(t reflect.Type) []reflect.StructField {
+	fields := []reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && embedded.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			// The index of promoted fields goes through the embedded field
+			for _, promoted := range JSONFields(embedded) {
+				promoted.Index = append([]int{i}, promoted.Index...)
+				fields = append(fields, promoted)
+			}
+		} else if field.PkgPath == "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Returns the name of the field in JSON, "-" if it is ignored, and whether
+// it is omitted when empty
+func JSONName(field reflect.StructField) (string, bool) {
+	split := strings.Split(field.Tag.Get("json"), ",")
+	name := split[0]
+	if name == "-" && len(split) == 1 {
+		return "-", false
+	} else if name == "" {
+		name = field.Name
+	}
+
+	for _, option := range split[1:] {
+		if option == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
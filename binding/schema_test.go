@@ -0,0 +1,74 @@
+package binding
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Node struct {
+	Name     string            `json:"name"`
+	Parent   *Node             `json:"parent"`
+	Children []Node            `json:"children,omitempty"`
+	Labels   map[string]string `json:"labels"`
+}
+
+type Metadata struct {
+	Created time.Time
+}
+
+type Document struct {
+	Metadata
+	Title  string  `json:"title"`
+	Root   Node    `json:"root"`
+	Score  float64 `json:"score,omitempty"`
+	Token  string  `json:"-" hermes:"header=Authorization"`
+	Query  string  `hermes:"query=q"`
+	Ignore string  `json:"-"`
+	hidden string
+}
+
+func TestJSONSchema(t *testing.T) {
+	raw, err := JSONSchema(reflect.TypeOf(&Document{}))
+	require.NoError(t, err)
+
+	schema := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(raw, &schema))
+	assert.Equal(t, JSONSchemaDraft, schema["$schema"])
+	assert.Equal(t, "Document", schema["title"])
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []interface{}{"Created", "title", "root"}, schema["required"])
+
+	properties, _ := json.Marshal(schema["properties"])
+	assert.JSONEq(t, `{
+		"Created": {"type": "string", "format": "date-time"},
+		"title": {"type": "string"},
+		"root": {"$ref": "#/definitions/binding.Node"},
+		"score": {"type": "number", "format": "double"},
+		"Query": {"type": "string", "x-hermes": ["query=q"]}
+	}`, string(properties))
+
+	node, _ := json.Marshal(schema["definitions"].(map[string]interface{})["binding.Node"])
+	assert.JSONEq(t, `{"type": "object", "required": ["name", "labels"], "properties": {
+		"name": {"type": "string"},
+		"parent": {"anyOf": [{"$ref": "#/definitions/binding.Node"}, {"type": "null"}]},
+		"children": {"type": ["array", "null"], "items": {"$ref": "#/definitions/binding.Node"}},
+		"labels": {"type": ["object", "null"], "additionalProperties": {"type": "string"}}
+	}}`, string(node))
+}
+
+func TestSchemaNullable(t *testing.T) {
+	generator := NewSchemaGenerator("#/components/schemas/")
+	generator.Nullable = true
+
+	assert.Equal(t, map[string]interface{}{"type": "integer", "format": "int64", "nullable": true}, generator.Schema(reflect.TypeOf(new(int))))
+	assert.Equal(t, map[string]interface{}{
+		"allOf":    []interface{}{map[string]interface{}{"$ref": "#/components/schemas/binding.Node"}},
+		"nullable": true,
+	}, generator.Schema(reflect.TypeOf(&Node{})))
+	assert.Contains(t, generator.Definitions, "binding.Node")
+}
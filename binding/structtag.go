@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -109,32 +110,96 @@ func (b StructTagBinding) Apply(req *http.Request, obj interface{}) error {
 }
 
 func (b StructTagBinding) BindDirective(ctx *gin.Context, obj interface{}, fieldname string, directive string) error {
-	split := strings.Split(directive, "=")
-	if len(split) != 2 {
-		return fmt.Errorf("Malformed struct tag: %v", directive)
+	tagkey, tagval, err := ParseDirective(directive)
+	if err != nil {
+		return err
 	}
-	tagkey, tagval := split[0], split[1]
 	operation, found := StructTagBinds[tagkey]
 	if !found {
 		return fmt.Errorf("Failed to resolve struct tag operation: %v", tagkey)
 	}
 
-	err := operation(ctx, obj, tagval, fieldname)
-	return err
+	return operation(ctx, obj, tagval, fieldname)
 }
 
 func (b StructTagBinding) ApplyDirective(req *http.Request, directive string, fieldvalue string) error {
-	split := strings.Split(directive, "=")
-	if len(split) != 2 {
-		return fmt.Errorf("Malformed struct tag: %v", directive)
+	tagkey, tagval, err := ParseDirective(directive)
+	if err != nil {
+		return err
 	}
-
-	tagkey, tagval := split[0], split[1]
 	operation, found := StructTagApps[tagkey]
 	if !found {
 		return fmt.Errorf("Failed to resolve struct tag operation: %v", tagkey)
 	}
 
-	err := operation(req, tagval, fieldvalue)
-	return err
+	return operation(req, tagval, fieldvalue)
+}
+
+// Splits a single directive of a hermes struct tag (e.g "query=limit")
+// into its key and value
+func ParseDirective(directive string) (string, string, error) {
+	split := strings.Split(directive, "=")
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return "", "", fmt.Errorf("Malformed struct tag: %v", directive)
+	}
+	return split[0], split[1], nil
+}
+
+// Returns the directives of the hermes struct tags of the given type,
+// keyed by field name
+func Directives(t reflect.Type) map[string][]string {
+	directives := map[string][]string{}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return directives
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if fielddirectives := FieldDirectives(field); len(fielddirectives) != 0 {
+			directives[field.Name] = fielddirectives
+		}
+	}
+	return directives
+}
+
+// Returns the directives of the hermes struct tag of the field
+func FieldDirectives(field reflect.StructField) []string {
+	directives := []string{}
+	for _, directive := range strings.Split(field.Tag.Get("hermes"), ",") {
+		if directive != "" {
+			directives = append(directives, directive)
+		}
+	}
+	return directives
+}
+
+// Checks that every hermes struct tag of the given type can be both
+// bound and applied
+func CheckStructTags(t reflect.Type) []error {
+	errs := []error{}
+	directives := Directives(t)
+	fieldnames := []string{}
+	for fieldname := range directives {
+		fieldnames = append(fieldnames, fieldname)
+	}
+	sort.Strings(fieldnames)
+
+	for _, fieldname := range fieldnames {
+		for _, directive := range directives[fieldname] {
+			tagkey, _, err := ParseDirective(directive)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Field %s of %v: %v", fieldname, t, err))
+				continue
+			}
+			_, bindable := StructTagBinds[tagkey]
+			_, appliable := StructTagApps[tagkey]
+			if !bindable || !appliable {
+				errs = append(errs, fmt.Errorf("Field %s of %v: unknown struct tag operation: %v", fieldname, t, tagkey))
+			}
+		}
+	}
+	return errs
 }
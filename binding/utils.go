@@ -11,7 +11,6 @@ import (
 
 	"github.com/fatih/structs"
 	"github.com/gin-gonic/gin"
-	"github.com/golang/glog"
 )
 
 // Binds the header <headername> to the field <fieldname> of obj
@@ -35,7 +34,7 @@ func BindQuery(ctx *gin.Context, obj interface{}, queryparam string, fieldname s
 	if len(vals) > 1 {
 		err := fmt.Errorf("Query parameter had multiple values; which is unsupported.")
 		if (QueryFlags | IGNORE_MULTIPLE_QUERYVALS) == 0 {
-			glog.Warningf("%v", err)
+			warn(ctx, "Ignoring extra query values", "query", queryparam, "values", len(vals))
 		} else {
 			return err
 		}
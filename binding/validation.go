@@ -0,0 +1,335 @@
+package binding
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Input types can implement Validatable to check what struct tags cannot
+// express. Validate is only called once the validate tags are satisfied
+type Validatable interface {
+	Validate() error
+}
+
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := []string{}
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s %s", field.Field, field.Message))
+	}
+	return "Invalid input: " + strings.Join(messages, "; ")
+}
+
+// The error returned by the Validate method of an input that callers were
+// about to send, so that they can tell invalid inputs from failed bindings
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// Validates objects after they were bound, and before they are applied to
+// requests so that callers do not send invalid inputs
+type ValidationBinding struct{}
+
+func (_ ValidationBinding) Bind(ctx *gin.Context, obj interface{}) error {
+	return Validate(obj)
+}
+
+func (_ ValidationBinding) Apply(req *http.Request, obj interface{}) error {
+	err := Validate(obj)
+	if _, ok := err.(*ValidationError); err != nil && !ok {
+		return &InputError{err}
+	}
+	return err
+}
+
+type validationRule func(v reflect.Value, arg string) (string, bool)
+
+// The rules of the validate struct tag, e.g `validate:"required,min=1"`.
+// Since regular expressions can contain commas, regex must be the last rule
+var ValidationRules = map[string]validationRule{
+	"required": validateRequired,
+	"min":      validateMin,
+	"max":      validateMax,
+	"oneof":    validateOneOf,
+	"regex":    validateRegex,
+}
+
+// Checks the validate struct tags of obj and of the structs it contains,
+// then calls Validate if obj is Validatable. Returns a *ValidationError
+// listing every invalid field
+func Validate(obj interface{}) error {
+	if obj == nil {
+		return nil
+	}
+	errs := validateValue(reflect.ValueOf(obj), "")
+	if len(errs) != 0 {
+		return &ValidationError{errs}
+	}
+	if casted, ok := obj.(Validatable); ok {
+		return casted.Validate()
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, path string) []FieldError {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	errs := []FieldError{}
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, field := range JSONFields(v.Type()) {
+			name, _ := JSONName(field)
+			if name == "-" {
+				name = field.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+
+			value, ok := fieldByIndex(v, field.Index)
+			if !ok {
+				continue
+			}
+			errs = append(errs, validateField(value, name, field.Tag.Get("validate"))...)
+			errs = append(errs, validateValue(value, name)...)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			errs = append(errs, validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return errs
+}
+
+// Same as FieldByIndex, but fails instead of panicking on nil embedded
+// pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func validateField(v reflect.Value, name string, tag string) []FieldError {
+	errs := []FieldError{}
+	for _, rule := range splitRules(tag) {
+		key, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			key, arg = rule[:i], rule[i+1:]
+		}
+
+		// Nil pointers only fail the required rule
+		value := v
+		for value.Kind() == reflect.Ptr && !value.IsNil() {
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Ptr && key != "required" {
+			continue
+		}
+
+		validate, found := ValidationRules[key]
+		if !found {
+			errs = append(errs, FieldError{name, key, "has an unknown validation rule"})
+			continue
+		}
+		if message, ok := validate(value, arg); !ok {
+			errs = append(errs, FieldError{name, key, message})
+		}
+	}
+	return errs
+}
+
+func splitRules(tag string) []string {
+	rules := []string{}
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			return append(rules, tag)
+		}
+		rule := tag
+		if i := strings.Index(tag, ","); i >= 0 {
+			rule, tag = tag[:i], tag[i+1:]
+		} else {
+			tag = ""
+		}
+		if rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// Returns the length of strings and collections, the value of numbers
+func measure(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), "characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), "elements", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	}
+	return 0, "", false
+}
+
+func validateRequired(v reflect.Value, _ string) (string, bool) {
+	if v.IsZero() {
+		return "is required", false
+	}
+	return "", true
+}
+
+func validateMin(v reflect.Value, arg string) (string, bool) {
+	return validateBound(v, arg, "at least", func(value, bound float64) bool { return value >= bound })
+}
+
+func validateMax(v reflect.Value, arg string) (string, bool) {
+	return validateBound(v, arg, "at most", func(value, bound float64) bool { return value <= bound })
+}
+
+func validateBound(v reflect.Value, arg string, relation string, compare func(value, bound float64) bool) (string, bool) {
+	bound, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return fmt.Sprintf("has an invalid bound: %v", arg), false
+	}
+	value, unit, ok := measure(v)
+	if !ok {
+		return fmt.Sprintf("cannot be bounded: %v", v.Type()), false
+	}
+	if compare(value, bound) {
+		return "", true
+	}
+	if unit != "" {
+		return fmt.Sprintf("must have %s %s %s", relation, arg, unit), false
+	}
+	return fmt.Sprintf("must be %s %s", relation, arg), false
+}
+
+func validateOneOf(v reflect.Value, arg string) (string, bool) {
+	options := strings.Split(arg, "|")
+	value := fmt.Sprint(v.Interface())
+	for _, option := range options {
+		if value == option {
+			return "", true
+		}
+	}
+	return fmt.Sprintf("must be one of %s", strings.Join(options, ", ")), false
+}
+
+var regexps = sync.Map{}
+
+func validateRegex(v reflect.Value, arg string) (string, bool) {
+	if v.Kind() != reflect.String {
+		return fmt.Sprintf("cannot be matched: %v", v.Type()), false
+	}
+	re, err := compileRegex(arg)
+	if err != nil {
+		return fmt.Sprintf("has an invalid regular expression: %v", err), false
+	}
+	if !re.MatchString(v.String()) {
+		return fmt.Sprintf("must match %s", arg), false
+	}
+	return "", true
+}
+
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	if re, found := regexps.Load(expr); found {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Store(expr, re)
+	return re, nil
+}
+
+// Reports unknown rules, invalid bounds and invalid regular expressions in
+// the validate struct tags of the type and of the structs it contains, the
+// same way Validate goes through them
+func CheckValidationTags(t reflect.Type) []error {
+	return checkValidationTags(t, map[reflect.Type]bool{})
+}
+
+func checkValidationTags(t reflect.Type, checked map[reflect.Type]bool) []error {
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || checked[t] {
+		return []error{}
+	}
+	// Types are checked once, recursive ones included
+	checked[t] = true
+
+	errs := []error{}
+	fields := JSONFields(t)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	for _, field := range fields {
+		for _, rule := range splitRules(field.Tag.Get("validate")) {
+			key, arg := rule, ""
+			if i := strings.Index(rule, "="); i >= 0 {
+				key, arg = rule[:i], rule[i+1:]
+			}
+
+			var err error
+			switch key {
+			case "required", "oneof":
+			case "min", "max":
+				_, err = strconv.ParseFloat(arg, 64)
+			case "regex":
+				_, err = compileRegex(arg)
+			default:
+				if _, found := ValidationRules[key]; !found {
+					err = fmt.Errorf("unknown validation rule")
+				}
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Field %s of %v: invalid validation rule %s: %v", field.Name, t, rule, err))
+			}
+		}
+	}
+	for _, field := range fields {
+		errs = append(errs, checkValidationTags(field.Type, checked)...)
+	}
+	return errs
+}
@@ -0,0 +1,111 @@
+package binding
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type Signup struct {
+	Name      string    `json:"name" validate:"required,min=2,max=8"`
+	Age       int       `validate:"min=18"`
+	Plan      string    `validate:"oneof=free|pro"`
+	Email     *string   `validate:"regex=^[a-z]+@[a-z]+\\.(com|org)$"`
+	Tags      []string  `validate:"max=2"`
+	Addresses []Address `json:"addresses"`
+	Referrer  *Address
+}
+
+type Transfer struct {
+	From, To string
+}
+
+func (t Transfer) Validate() error {
+	if t.From == t.To {
+		return fmt.Errorf("Cannot transfer to the same account")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	email := "jo@example.com"
+	valid := &Signup{Name: "jo", Age: 18, Plan: "pro", Email: &email, Tags: []string{"a"}, Addresses: []Address{{"Paris"}}}
+	assert.NoError(t, Validate(valid))
+
+	email = "not an email"
+	err := Validate(&Signup{Name: "abcdefghi", Age: 12, Plan: "gold", Email: &email, Tags: []string{"a", "b", "c"},
+		Addresses: []Address{{"Paris"}, {}}, Referrer: &Address{}})
+	require.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, []FieldError{
+		{"name", "max", "must have at most 8 characters"},
+		{"Age", "min", "must be at least 18"},
+		{"Plan", "oneof", "must be one of free, pro"},
+		{"Email", "regex", "must match ^[a-z]+@[a-z]+\\.(com|org)$"},
+		{"Tags", "max", "must have at most 2 elements"},
+		{"addresses[1].city", "required", "is required"},
+		{"Referrer.city", "required", "is required"},
+	}, err.(*ValidationError).Fields)
+
+	// Optional fields are not validated when nil
+	err = Validate(&Signup{Age: 20, Plan: "free"})
+	assert.Equal(t, []FieldError{{"name", "required", "is required"}, {"name", "min", "must have at least 2 characters"}}, err.(*ValidationError).Fields)
+}
+
+func TestValidatable(t *testing.T) {
+	assert.NoError(t, Validate(&Transfer{"a", "b"}))
+	assert.EqualError(t, Validate(&Transfer{"a", "a"}), "Cannot transfer to the same account")
+	assert.NoError(t, ValidationBinding{}.Apply(nil, &Transfer{"a", "b"}))
+	assert.IsType(t, &InputError{}, ValidationBinding{}.Apply(nil, &Transfer{"a", "a"}))
+	assert.IsType(t, &ValidationError{}, ValidationBinding{}.Apply(nil, &Signup{}))
+}
+
+func TestCheckValidationTags(t *testing.T) {
+	assert.Empty(t, CheckValidationTags(reflect.TypeOf(Signup{})))
+
+	type Broken struct {
+		A int    `validate:"min=one"`
+		B string `validate:"regex=(("`
+		C string `validate:"unique"`
+	}
+	assert.Len(t, CheckValidationTags(reflect.TypeOf(&Broken{})), 3)
+
+	// Nested structs are checked like Validate goes through them
+	type Node struct {
+		Children []*Node
+		Broken   *Broken
+	}
+	assert.Len(t, CheckValidationTags(reflect.TypeOf(&struct{ Nodes []Node }{})), 3)
+}
+
+type Base struct {
+	A, B string
+}
+
+type Audited struct {
+	Author string `validate:"required"`
+}
+
+type Embedding struct {
+	Base
+	*Audited
+	C string `validate:"required"`
+}
+
+func TestValidateEmbedded(t *testing.T) {
+	assert.NoError(t, Validate(&struct{ Base }{}))
+
+	err := Validate(&Embedding{Base: Base{"a", "b"}})
+	require.Error(t, err)
+	assert.Equal(t, []FieldError{{"C", "required", "is required"}}, err.(*ValidationError).Fields)
+
+	err = Validate(&Embedding{Audited: &Audited{}, C: "c"})
+	require.Error(t, err)
+	assert.Equal(t, []FieldError{{"Author", "required", "is required"}}, err.(*ValidationError).Fields)
+}
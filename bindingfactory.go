@@ -27,5 +27,6 @@ func AllBindingFactory(params, queries []string, headers map[string]string) bind
 	url := &binding.URLBinding{params, queries}
 	json := &binding.JSONBinding{}
 	plugin := binding.PluginBinding{}
-	return binding.NewSequentialBinding(tags, header, url, json, plugin)
+	validation := binding.ValidationBinding{}
+	return binding.NewSequentialBinding(tags, header, url, json, plugin, validation)
 }
@@ -1,11 +1,21 @@
 package hermes
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apourchet/hermes/binding"
 )
 
 type ICaller interface {
@@ -16,8 +26,30 @@ type Caller struct {
 	Client   IClient
 	Resolve  Resolver
 	Bindings BindingFactory
+	Encoders []Encoder
 
 	Scheme string
+	Accept string
+
+	// Attaches the credentials of the called service to requests
+	Credentials Credentials
+
+	// Signs requests once they are ready to be sent
+	Signer *RequestSigner
+
+	// The content encoding of request bodies, like gzip. Bodies are sent
+	// uncompressed if empty
+	Compression string
+
+	// Records the metrics of the calls if set, like in the registry of the
+	// router that exports them
+	Metrics *MetricRegistry
+
+	// Traces the calls if set
+	Tracer Tracer
+
+	// Logs failed calls as warnings and the others as debug entries if set
+	Logger Logger
 
 	callable ICallable
 }
@@ -27,69 +59,279 @@ func NewCaller(callable ICallable) *Caller {
 	out.Client = DefaultClient
 	out.Resolve = DefaultResolver
 	out.Bindings = DefaultBindingFactory
+	out.Encoders = DefaultEncoders
 	out.Scheme = "http"
+	out.Accept = JSONEncoder{}.MediaType()
+	out.Logger = DefaultLogger
 	out.callable = callable
 	return out
 }
 
-func (caller *Caller) Call(ctx context.Context, methodname string, in, out interface{}) (int, error) {
+func (caller *Caller) Call(ctx context.Context, methodname string, in, out interface{}) (code int, err error) {
+	if caller.Logger != nil {
+		start := time.Now()
+		defer func() { caller.logCall(ctx, methodname, start, code, err) }()
+	}
+	var resp *http.Response
+	if caller.Metrics != nil {
+		done := caller.Metrics.calling(caller.callable.SNI(), methodname)
+		defer func() { done(resp) }()
+	}
+	ctx, finish := caller.startSpan(ctx, methodname)
+	defer func() { finish(code, err) }()
+
+	_, resp, code, err = caller.execute(ctx, methodname, in)
+	if err != nil {
+		return code, err
+	}
+	defer resp.Body.Close()
+
+	// Read in response
+	reader, err := binding.Decompress(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("Client failed to decompress response body: %v", err)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("Client failed read response body: %v", err)
+	}
+
+	// Deal with response
+	if resp.StatusCode/100 == 2 {
+		// Handlers that return a nil output answer with no content
+		if out != nil && resp.StatusCode != http.StatusNoContent && len(body) != 0 {
+			encoder := EncoderFor(caller.Encoders, resp.Header.Get("Content-Type"))
+			if encoder == nil {
+				encoder = JSONEncoder{}
+			}
+			if err := encoder.Decode(bytes.NewReader(body), out); err != nil {
+				return resp.StatusCode, fmt.Errorf("Client failed to unmarshal response into output: %v", err)
+			}
+		}
+		return resp.StatusCode, nil
+	}
+
+	// There was an error
+	return resp.StatusCode, parseError(resp.StatusCode, resp.Header, body)
+}
+
+// Calls a streaming endpoint. The receive function gets called with a new
+// *OutputType for every item of the stream, as they arrive. Returning an
+// error from it stops the stream
+func (caller *Caller) Stream(ctx context.Context, methodname string, in interface{}, recv func(item interface{}) error) (code int, err error) {
+	if caller.Logger != nil {
+		start := time.Now()
+		defer func() { caller.logCall(ctx, methodname, start, code, err) }()
+	}
+	var ep *Endpoint
+	var resp *http.Response
+	if caller.Metrics != nil {
+		done := caller.Metrics.calling(caller.callable.SNI(), methodname)
+		defer func() { done(resp) }()
+	}
+	ctx, finish := caller.startSpan(ctx, methodname)
+	defer func() { finish(code, err) }()
+
+	ep, resp, code, err = caller.execute(ctx, methodname, in)
+	if err != nil {
+		return code, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		reader, err := binding.Decompress(resp.Header.Get("Content-Encoding"), resp.Body)
+		if err != nil {
+			return resp.StatusCode, fmt.Errorf("Client failed to decompress response body: %v", err)
+		}
+		body, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return resp.StatusCode, fmt.Errorf("Client failed read response body: %v", err)
+		}
+		return resp.StatusCode, parseError(resp.StatusCode, resp.Header, body)
+	}
+
+	if ep.Streaming == "" || ep.OutputType == nil {
+		return resp.StatusCode, fmt.Errorf("Client cannot stream endpoint '%s'", ep.Handler)
+	}
+	newItem := func() interface{} { return reflect.New(ep.OutputType).Interface() }
+	if ep.Streaming == SSE {
+		return resp.StatusCode, readEvents(resp.Body, newItem, recv)
+	}
+	return resp.StatusCode, readNDJSON(resp, newItem, recv)
+}
+
+// Builds the request for the endpoint and executes it. The status code is
+// only meaningful when the request could not be executed
+func (caller *Caller) execute(ctx context.Context, methodname string, in interface{}) (*Endpoint, *http.Response, int, error) {
 	callable := caller.callable
 
 	// Get endpoint
 	ep, err := findEndpointByHandler(callable, methodname)
 	if err != nil {
-		return http.StatusBadRequest, fmt.Errorf("Client failed to find endpoint: %v", err)
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("Client failed to find endpoint: %v", err)
 	}
 
 	// Resolve URL
-	url, err := caller.Resolve(callable.SNI(), ep.Path)
+	url, err := caller.Resolve(callable.SNI(), fullPath(callable, ep))
 	if err != nil {
-		return http.StatusNotFound, fmt.Errorf("Client failed to resolve url: %v", err)
+		return ep, nil, http.StatusNotFound, fmt.Errorf("Client failed to resolve url: %v", err)
 	}
 
 	// Create new request
 	req, err := http.NewRequest(ep.Method, fmt.Sprintf("%s://%s", caller.Scheme, url), nil)
 	if err != nil {
-		return http.StatusBadRequest, fmt.Errorf("Client failed to create new http request")
+		return ep, nil, http.StatusBadRequest, fmt.Errorf("Client failed to create new http request")
 	}
 
 	// Use bindings on request
 	err = caller.Bindings(ep.Params, ep.Queries, ep.Headers).Apply(req, in)
-	if err != nil {
-		return http.StatusInternalServerError, fmt.Errorf("Client failed to apply a binding: %v", err)
+	if err != nil && caller.Metrics != nil {
+		caller.Metrics.Add("hermes_client_binding_failures_total", metricLabels("sni", callable.SNI(), "handler", ep.Handler), 1)
+	}
+	if err != nil && caller.Tracer != nil {
+		SpanFrom(ctx).SetAttribute("hermes.binding_error", err.Error())
+	}
+	invalid := &binding.InputError{}
+	if _, ok := err.(*binding.ValidationError); ok {
+		return ep, nil, http.StatusBadRequest, err
+	} else if errors.As(err, &invalid) {
+		// Rejected by the Validate method of the input, as the server would
+		code, e := ToError(http.StatusBadRequest, invalid.Err)
+		return ep, nil, code, e
+	} else if err != nil {
+		return ep, nil, http.StatusInternalServerError, fmt.Errorf("Client failed to apply a binding: %v", err)
+	}
+	if caller.Compression != "" {
+		if err := compressRequest(req, caller.Compression); err != nil {
+			return ep, nil, http.StatusBadRequest, fmt.Errorf("Client failed to compress request: %v", err)
+		}
+	}
+	if caller.Credentials != nil {
+		if err := caller.Credentials(ctx, callable.SNI(), req); err != nil {
+			return ep, nil, http.StatusUnauthorized, fmt.Errorf("Client failed to attach credentials: %v", err)
+		}
+	}
+	if ep.Streaming != "" {
+		req.Header.Set("Accept", string(ep.Streaming))
+	} else {
+		if caller.Accept != "" {
+			req.Header.Set("Accept", caller.Accept)
+		}
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
 	}
 
-	// Transfer request ID to call
+	// Transfer request ID and trace to call
 	TransferRequestID(ctx, req)
+	if span := SpanFrom(ctx); span != nil {
+		injectSpanContext(req.Header, span.Context)
+	}
+
+	if caller.Signer != nil {
+		if err := caller.Signer.Sign(req); err != nil {
+			return ep, nil, http.StatusInternalServerError, fmt.Errorf("Client failed to sign request: %v", err)
+		}
+	}
 
 	// Execute request
 	resp, err := caller.Client.Exec(ctx, req)
 	if err != nil {
-		return http.StatusInternalServerError, fmt.Errorf("Client failed execute request: %v", err)
+		return ep, nil, http.StatusInternalServerError, fmt.Errorf("Client failed execute request: %v", err)
 	}
-	defer resp.Body.Close()
+	return ep, resp, resp.StatusCode, nil
+}
 
-	// Read in response
-	body, err := ioutil.ReadAll(resp.Body)
+// Reconstructs the Error sent by the server, with the registered error of
+// the same code as its cause so that errors.Is works across the wire
+func parseError(status int, header http.Header, body []byte) error {
+	tmp := &Error{}
+	err := json.Unmarshal(body, tmp)
 	if err != nil {
-		return resp.StatusCode, fmt.Errorf("Client failed read response body: %v", err)
+		return fmt.Errorf("Client failed to parse error response: %v", err)
+	}
+	tmp.Status = status
+	if tmp.RetryAfter == 0 {
+		tmp.RetryAfter, _ = strconv.Atoi(header.Get("Retry-After"))
 	}
 
-	// Deal with response
-	if resp.StatusCode/100 == 2 {
-		if out != nil {
-			if err := json.Unmarshal(body, out); err != nil {
-				return resp.StatusCode, fmt.Errorf("Client failed to unmarshal response into output: %v", err)
+	errorMappingsLock.RLock()
+	defer errorMappingsLock.RUnlock()
+	for _, mapping := range errorMappings {
+		if tmp.Code != "" && mapping.code == tmp.Code {
+			tmp.cause = mapping.target
+			if tmp.Status == 0 {
+				tmp.Status = mapping.status
 			}
+			break
 		}
-		return resp.StatusCode, nil
 	}
+	return tmp
+}
 
-	// There was an error
-	tmp := &Error{}
-	err = json.Unmarshal(body, tmp)
-	if err != nil {
-		return resp.StatusCode, fmt.Errorf("Client failed to parse error response: %v", err)
+// Reconstructs the error sent in-band by a stream. Its status is the one
+// the handler failed with, or the status of its registered code if the
+// server did not send it
+func parseStreamError(content []byte) error {
+	wire := struct{ Status int }{}
+	json.Unmarshal(content, &wire)
+	return parseError(wire.Status, nil, content)
+}
+
+func readNDJSON(resp *http.Response, newItem func() interface{}, recv func(interface{}) error) error {
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		item := newItem()
+		err := decoder.Decode(item)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("Client failed to decode stream item: %v", err)
+		}
+		if err := recv(item); err != nil {
+			return err
+		}
+	}
+
+	// Trailers are only available once the body was read entirely
+	if trailer := resp.Trailer.Get(StreamErrorTrailer); trailer != "" {
+		return parseStreamError([]byte(trailer))
+	}
+	return nil
+}
+
+func readEvents(body io.Reader, newItem func() interface{}, recv func(interface{}) error) error {
+	reader := bufio.NewReader(body)
+	event, data := "", []string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("Client failed to read event stream: %v", err)
+		}
+		eof := err == io.EOF
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(line[len("event:"):])
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(line[len("data:"):], " "))
+		case line == "" && len(data) != 0:
+			// Blank lines dispatch the event
+			content := []byte(strings.Join(data, "\n"))
+			if event == "error" {
+				return parseStreamError(content)
+			}
+			item := newItem()
+			if err := json.Unmarshal(content, item); err != nil {
+				return fmt.Errorf("Client failed to decode stream item: %v", err)
+			}
+			if err := recv(item); err != nil {
+				return err
+			}
+			event, data = "", []string{}
+		}
+
+		if eof {
+			return nil
+		}
 	}
-	return resp.StatusCode, tmp
 }
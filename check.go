@@ -0,0 +1,222 @@
+package hermes
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apourchet/hermes/binding"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ginContextType = reflect.TypeOf(&gin.Context{})
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Aggregates every problem found in the EndpointMap of a service
+type EndpointMapError struct {
+	Errors []error
+}
+
+func (e *EndpointMapError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("Invalid endpoint map (%d problems): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Checks the EndpointMap of the server against the methods of its type
+// so that a broken service definition fails before serving any request
+func checkEndpoints(server Server) error {
+	errs := []error{}
+	handlerType := reflect.TypeOf(server)
+	handlers := map[string]bool{}
+	routes := map[string][]*Endpoint{}
+
+	for _, ep := range server.Endpoints() {
+		if handlers[ep.Handler] {
+			errs = append(errs, fmt.Errorf("Endpoint '%s' is declared more than once", ep.Handler))
+		}
+		handlers[ep.Handler] = true
+
+		path := fullPath(server, ep)
+		for _, other := range routes[ep.Method] {
+			otherPath := fullPath(server, other)
+			if pathShape(path) == pathShape(otherPath) {
+				errs = append(errs, fmt.Errorf("Endpoints '%s' and '%s' are both mapped to %s %s", other.Handler, ep.Handler, ep.Method, path))
+				break
+			}
+			if segment, otherSegment, found := wildcardConflict(path, otherPath); found {
+				errs = append(errs, fmt.Errorf("Endpoints '%s' and '%s' conflict: %s in %s %s cannot share its position with %s in %s",
+					other.Handler, ep.Handler, segment, ep.Method, path, otherSegment, otherPath))
+				break
+			}
+		}
+		routes[ep.Method] = append(routes[ep.Method], ep)
+
+		errs = append(errs, checkPathParams(server, ep)...)
+		for _, err := range binding.CheckStructTags(ep.InputType) {
+			errs = append(errs, fmt.Errorf("Endpoint '%s': %v", ep.Handler, err))
+		}
+		for _, err := range binding.CheckValidationTags(ep.InputType) {
+			errs = append(errs, fmt.Errorf("Endpoint '%s': %v", ep.Handler, err))
+		}
+
+		method, ok := handlerType.MethodByName(ep.Handler)
+		if !ok {
+			errs = append(errs, fmt.Errorf("Endpoint '%s' does not match any method of the type %v", ep.Handler, handlerType))
+			continue
+		}
+		if _, err := checkSignature(ep, method); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) != 0 {
+		return &EndpointMapError{errs}
+	}
+	return nil
+}
+
+// Returns the names of the parameters (":name" or "*name") of a path
+func pathParams(path string) map[string]bool {
+	params := map[string]bool{}
+	for _, segment := range strings.Split(path, "/") {
+		if isWildcard(segment) {
+			params[segment[1:]] = true
+		}
+	}
+	return params
+}
+
+func isWildcard(segment string) bool {
+	return strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*")
+}
+
+// Returns the path without the names of its wildcards. Gin cannot tell
+// apart the paths that have the same shape
+func pathShape(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if isWildcard(segment) {
+			segments[i] = segment[:1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Gin cannot route the paths of a method that part ways at a wildcard, like
+// /users/:id and /users/me, or /users/:id and /users/:uid/posts. Returns
+// the segments where they do
+func wildcardConflict(path, other string) (string, string, bool) {
+	segments, otherSegments := strings.Split(path, "/"), strings.Split(other, "/")
+	for i := 0; i < len(segments) && i < len(otherSegments); i++ {
+		if segments[i] == otherSegments[i] {
+			continue
+		}
+		if isWildcard(segments[i]) || isWildcard(otherSegments[i]) {
+			return segments[i], otherSegments[i], true
+		}
+		return "", "", false
+	}
+	return "", "", false
+}
+
+func checkPathParams(server Server, ep *Endpoint) []error {
+	errs := []error{}
+	path := fullPath(server, ep)
+	params := pathParams(path)
+	for _, param := range ep.Params {
+		if !params[param] {
+			errs = append(errs, fmt.Errorf("Endpoint '%s' declares parameter '%s' which is not in path %s", ep.Handler, param, path))
+		}
+	}
+
+	directives := binding.Directives(ep.InputType)
+	fieldnames := make([]string, 0, len(directives))
+	for fieldname := range directives {
+		fieldnames = append(fieldnames, fieldname)
+	}
+	sort.Strings(fieldnames)
+	for _, fieldname := range fieldnames {
+		for _, directive := range directives[fieldname] {
+			tagkey, tagval, err := binding.ParseDirective(directive)
+			if err == nil && tagkey == "path" && !params[tagval] {
+				errs = append(errs, fmt.Errorf("Endpoint '%s': field %s is bound to parameter '%s' which is not in path %s", ep.Handler, fieldname, tagval, path))
+			}
+		}
+	}
+	return errs
+}
+
+// The shapes of handlers. Handlers that do not return a status get 200 on
+// success, 204 if they have no output, and the status of their error
+type handlerShape int
+
+const (
+	// func (ctx context.Context, [in *InputType], [out *OutputType]) (int, error)
+	statusShape handlerShape = iota
+	// func (ctx context.Context, [in *InputType]) (*OutputType, error)
+	outputShape
+	// func (ctx context.Context, [in *InputType]) error
+	errorShape
+)
+
+// Returns the shape of the handler. Streaming endpoints take a send
+// function instead of the output pointer:
+// func (ctx context.Context, [in *InputType], send func(*OutputType) error) (int, error)
+// func (ctx context.Context, [in *InputType], send func(*OutputType) error) error
+func checkSignature(ep *Endpoint, method reflect.Method) (handlerShape, error) {
+	mtype := method.Type
+	shape := statusShape
+	if mtype.NumOut() == 1 && mtype.Out(0) == errorType {
+		shape = errorShape
+	} else if mtype.NumOut() == 2 && mtype.Out(0).Kind() != reflect.Int && mtype.Out(1) == errorType {
+		shape = outputShape
+	}
+
+	expected := []reflect.Type{}
+	if ep.InputType != nil {
+		expected = append(expected, reflect.PtrTo(ep.InputType))
+	}
+	if ep.Streaming != "" {
+		if ep.Streaming != NDJSON && ep.Streaming != SSE {
+			return shape, fmt.Errorf("Endpoint '%s' has an unknown stream format %s", ep.Handler, ep.Streaming)
+		}
+		if ep.OutputType == nil {
+			return shape, fmt.Errorf("Streaming endpoint '%s' needs an output type", ep.Handler)
+		}
+		send := reflect.FuncOf([]reflect.Type{reflect.PtrTo(ep.OutputType)}, []reflect.Type{errorType}, false)
+		expected = append(expected, send)
+	} else if ep.OutputType != nil && shape == statusShape {
+		expected = append(expected, reflect.PtrTo(ep.OutputType))
+	}
+
+	// The receiver is the first argument of the method
+	if mtype.NumIn() != 2+len(expected) {
+		return shape, fmt.Errorf("Handler '%s' should take %d arguments, takes %d", ep.Handler, 1+len(expected), mtype.NumIn()-1)
+	}
+	if !ginContextType.AssignableTo(mtype.In(1)) {
+		return shape, fmt.Errorf("Handler '%s' should take a context.Context as its first argument, takes %v", ep.Handler, mtype.In(1))
+	}
+	for i, argtype := range expected {
+		if mtype.In(2+i) != argtype {
+			return shape, fmt.Errorf("Handler '%s' argument %d should be %v, is %v", ep.Handler, 2+i, argtype, mtype.In(2+i))
+		}
+	}
+
+	switch {
+	case shape == outputShape && (ep.Streaming != "" || ep.OutputType == nil):
+		return shape, fmt.Errorf("Handler '%s' should not return an output", ep.Handler)
+	case shape == outputShape && mtype.Out(0) != reflect.PtrTo(ep.OutputType):
+		return shape, fmt.Errorf("Handler '%s' should return (%v, error)", ep.Handler, reflect.PtrTo(ep.OutputType))
+	case shape == errorShape && ep.OutputType != nil && ep.Streaming == "":
+		return shape, fmt.Errorf("Handler '%s' should return (%v, error)", ep.Handler, reflect.PtrTo(ep.OutputType))
+	case shape == statusShape && (mtype.NumOut() != 2 || mtype.Out(0).Kind() != reflect.Int || mtype.Out(1) != errorType):
+		return shape, fmt.Errorf("Handler '%s' should return (int, error)", ep.Handler)
+	}
+	return shape, nil
+}
@@ -0,0 +1,124 @@
+package hermes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type BrokenService struct{}
+
+type BadTags struct {
+	A string `hermes:"query"`
+	B string `hermes:"body=b"`
+	C string `hermes:"path=c"`
+}
+
+func (_ BrokenService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Good", "GET", "/good/:id", Action{}, nil).Param("id"),
+		hermes.EP("Good", "GET", "/good/again", nil, nil),
+		hermes.EP("SamePath", "GET", "/good/:id", nil, nil),
+		hermes.EP("MissingParam", "GET", "/missing", Action{}, nil).Param("action"),
+		hermes.EP("Tagged", "GET", "/tagged", BadTags{}, nil),
+		hermes.EP("WrongInput", "GET", "/wronginput", Inbound{}, nil),
+		hermes.EP("WrongReturn", "GET", "/wrongreturn", nil, nil),
+		hermes.EP("NotAMethod", "GET", "/notamethod", nil, nil),
+	}
+}
+
+func (_ BrokenService) Good(ctx context.Context, in *Action) (int, error) { return http.StatusOK, nil }
+func (_ BrokenService) SamePath(ctx context.Context) (int, error)         { return http.StatusOK, nil }
+func (_ BrokenService) MissingParam(ctx context.Context, in *Action) (int, error) {
+	return http.StatusOK, nil
+}
+func (_ BrokenService) Tagged(ctx context.Context, in *BadTags) (int, error) {
+	return http.StatusOK, nil
+}
+func (_ BrokenService) WrongInput(ctx context.Context, in *Action) (int, error) {
+	return http.StatusOK, nil
+}
+func (_ BrokenService) WrongReturn(ctx context.Context) (int, string) { return 0, "" }
+
+func TestServeRejectsBrokenEndpointMap(t *testing.T) {
+	engine := gin.New()
+	err := hermes.NewRouter(BrokenService{}).Serve(engine)
+	require.Error(t, err)
+
+	mapErr, ok := err.(*hermes.EndpointMapError)
+	require.True(t, ok)
+	assert.Len(t, mapErr.Errors, 11)
+	for _, fragment := range []string{
+		"'Good' is declared more than once",
+		"again in GET /good/again cannot share its position with :id in /good/:id",
+		"Handler 'Good' should take 1 arguments, takes 2",
+		"are both mapped to GET /good/:id",
+		"parameter 'action' which is not in path /missing",
+		"Malformed struct tag: query",
+		"unknown struct tag operation: body",
+		"parameter 'c' which is not in path /tagged",
+		"Handler 'WrongInput' argument 2",
+		"Handler 'WrongReturn' should return (int, error)",
+		"'NotAMethod' does not match any method",
+	} {
+		assert.Contains(t, err.Error(), fragment)
+	}
+
+	// Nothing gets registered when the map is invalid
+	assert.Empty(t, engine.Routes())
+}
+
+type WildcardService struct{}
+
+type UserPaths struct {
+	B string `hermes:"path=b"`
+	A string `hermes:"path=a"`
+	C string `hermes:"path=c"`
+}
+
+func (_ WildcardService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("ByID", "GET", "/users/:id", nil, nil),
+		hermes.EP("ByName", "GET", "/users/:name", nil, nil),
+		hermes.EP("Posts", "GET", "/users/:uid/posts", nil, nil),
+		hermes.EP("Files", "GET", "/users/*path", nil, nil),
+		hermes.EP("Delete", "DELETE", "/users/:uid", nil, nil),
+		hermes.EP("Unbound", "GET", "/unbound", UserPaths{}, nil),
+	}
+}
+
+func (_ WildcardService) ByID(ctx context.Context) (int, error)   { return http.StatusOK, nil }
+func (_ WildcardService) ByName(ctx context.Context) (int, error) { return http.StatusOK, nil }
+func (_ WildcardService) Posts(ctx context.Context) (int, error)  { return http.StatusOK, nil }
+func (_ WildcardService) Files(ctx context.Context) (int, error)  { return http.StatusOK, nil }
+func (_ WildcardService) Delete(ctx context.Context) (int, error) { return http.StatusOK, nil }
+func (_ WildcardService) Unbound(ctx context.Context, in *UserPaths) (int, error) {
+	return http.StatusOK, nil
+}
+
+func TestServeRejectsConflictingWildcards(t *testing.T) {
+	engine := gin.New()
+	err := hermes.NewRouter(WildcardService{}).Serve(engine)
+	require.Error(t, err)
+
+	mapErr, ok := err.(*hermes.EndpointMapError)
+	require.True(t, ok)
+	messages := []string{}
+	for _, err := range mapErr.Errors {
+		messages = append(messages, err.Error())
+	}
+	assert.Equal(t, []string{
+		"Endpoints 'ByID' and 'ByName' are both mapped to GET /users/:name",
+		"Endpoints 'ByID' and 'Posts' conflict: :uid in GET /users/:uid/posts cannot share its position with :id in /users/:id",
+		"Endpoints 'ByID' and 'Files' conflict: *path in GET /users/*path cannot share its position with :id in /users/:id",
+		"Endpoint 'Unbound': field A is bound to parameter 'a' which is not in path /unbound",
+		"Endpoint 'Unbound': field B is bound to parameter 'b' which is not in path /unbound",
+		"Endpoint 'Unbound': field C is bound to parameter 'c' which is not in path /unbound",
+	}, messages)
+	assert.Empty(t, engine.Routes())
+}
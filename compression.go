@@ -0,0 +1,90 @@
+package hermes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Responses smaller than that are not worth compressing
+var DefaultCompressionThreshold = 1024
+
+// The content encodings of responses and caller requests, by preference
+var compressors = []struct {
+	encoding string
+	writer   func(w io.Writer) io.WriteCloser
+}{
+	{"gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+	{"deflate", func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }},
+}
+
+func compress(encoding string, content []byte) ([]byte, bool) {
+	for _, compressor := range compressors {
+		if compressor.encoding != encoding {
+			continue
+		}
+		buf := &bytes.Buffer{}
+		writer := compressor.writer(buf)
+		writer.Write(content)
+		if err := writer.Close(); err != nil {
+			return nil, false
+		}
+		return buf.Bytes(), true
+	}
+	return nil, false
+}
+
+// Picks the preferred encoding the Accept-Encoding header allows, empty if
+// none of them is
+func negotiateCompression(acceptEncoding string) string {
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		encoding := strings.ToLower(strings.TrimSpace(params[0]))
+		quality := 1.0
+		for _, param := range params[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				quality, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		accepted[encoding] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, compressor := range compressors {
+		quality, found := accepted[compressor.encoding]
+		if !found {
+			quality, found = accepted["*"]
+		}
+		if found && quality > bestQuality {
+			best, bestQuality = compressor.encoding, quality
+		}
+	}
+	return best
+}
+
+// Compresses the body of the request with the encoding
+func compressRequest(req *http.Request, encoding string) error {
+	if req.Body == nil || req.ContentLength == 0 {
+		return nil
+	}
+	content, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+	compressed, ok := compress(encoding, content)
+	if !ok {
+		return fmt.Errorf("Unsupported content encoding: %s", encoding)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(compressed))
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", encoding)
+	return nil
+}
@@ -0,0 +1,89 @@
+package hermes_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/apourchet/hermes/binding"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ArchiveService struct{}
+
+func (_ ArchiveService) SNI() string { return "UNUSED" }
+
+func (_ ArchiveService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Echo", "POST", "/echo", Inbound{}, Inbound{}),
+	}
+}
+
+func (_ ArchiveService) Echo(ctx context.Context, in *Inbound) (*Inbound, error) {
+	return in, nil
+}
+
+func TestCompression(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(ArchiveService{}).Serve(engine))
+
+	large := strings.Repeat("hermes ", 1000)
+	echo := func(message, acceptEncoding string) *httptest.ResponseRecorder {
+		content, _ := json.Marshal(&Inbound{message})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/echo", bytes.NewReader(content))
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := echo(large, "gzip")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+	assert.True(t, w.Body.Len() < len(large)/10)
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Message": "`+large+`"}`, string(content))
+
+	assert.Equal(t, "deflate", echo(large, "gzip;q=0, deflate").Header().Get("Content-Encoding"))
+	assert.Equal(t, "", echo(large, "br").Header().Get("Content-Encoding"))
+	assert.Equal(t, "", echo(large, "").Header().Get("Content-Encoding"))
+	assert.Equal(t, "", echo("small", "gzip").Header().Get("Content-Encoding"))
+
+	// Callers compress their requests and decompress responses
+	caller := hermes.NewCaller(ArchiveService{})
+	caller.Client = &hermes.MockClient{engine}
+	caller.Compression = "gzip"
+	out := &Inbound{}
+	code, err := caller.Call(context.Background(), "Echo", &Inbound{large}, out)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, large, out.Message)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/echo", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDecompress(t *testing.T) {
+	_, err := binding.Decompress("br", strings.NewReader(""))
+	assert.Error(t, err)
+
+	reader, err := binding.Decompress("identity", strings.NewReader("plain"))
+	require.NoError(t, err)
+	content, _ := ioutil.ReadAll(reader)
+	assert.Equal(t, "plain", string(content))
+}
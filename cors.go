@@ -0,0 +1,216 @@
+package hermes
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apourchet/hermes/binding"
+	"github.com/gin-gonic/gin"
+)
+
+// Lets browsers call the endpoints of a Router from other origins. The
+// Router answers preflight requests for every path of its EndpointMap with
+// the methods registered for it. Besides AllowedHeaders, browsers can send
+// Content-Type, the headers bound to inputs and Authorization if the
+// Router authenticates requests
+type CORSPolicy struct {
+	// "*" allows any origin
+	AllowedOrigins   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+func (policy *CORSPolicy) allowsAnyOrigin() bool {
+	for _, allowed := range policy.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Credentials cannot be shared with any origin, since any site could then
+// read responses with the credentials of its visitors
+func (policy *CORSPolicy) validate() error {
+	if policy.AllowCredentials && policy.allowsAnyOrigin() {
+		return fmt.Errorf("CORS policy cannot allow credentials from any origin")
+	}
+	return nil
+}
+
+// Sets the headers of responses to requests from origins that are not
+// allowed. Responses still depend on the origin unless all are allowed
+func (policy *CORSPolicy) setDisallowedHeaders(ctx *gin.Context) {
+	if !policy.allowsAnyOrigin() {
+		ctx.Writer.Header().Add("Vary", "Origin")
+	}
+}
+
+func (policy *CORSPolicy) allowsOrigin(origin string) bool {
+	for _, allowed := range policy.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Sets the headers of responses to requests from allowed origins
+func (policy *CORSPolicy) setOriginHeaders(ctx *gin.Context, origin string) {
+	header := ctx.Writer.Header()
+	header.Add("Vary", "Origin")
+	header.Set("Access-Control-Allow-Origin", origin)
+	if policy.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(policy.ExposedHeaders) != 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+	}
+}
+
+// Returns the origin of the request if it is allowed, empty otherwise
+func (policy *CORSPolicy) origin(ctx *gin.Context) string {
+	origin := ctx.Request.Header.Get("Origin")
+	if origin == "" || !policy.allowsOrigin(origin) {
+		return ""
+	}
+	return origin
+}
+
+func (policy *CORSPolicy) preflight(methods []string, headers []string) gin.HandlerFunc {
+	allowedMethods := map[string]bool{}
+	for _, method := range methods {
+		allowedMethods[method] = true
+	}
+	allowedHeaders := map[string]bool{}
+	for _, header := range headers {
+		allowedHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+
+	return func(ctx *gin.Context) {
+		origin := policy.origin(ctx)
+		if origin == "" {
+			policy.setDisallowedHeaders(ctx)
+			ctx.JSON(http.StatusForbidden, PermissionDenied("Origin '%s' is not allowed", ctx.Request.Header.Get("Origin")))
+			return
+		}
+		method := ctx.Request.Header.Get("Access-Control-Request-Method")
+		if !allowedMethods[method] {
+			ctx.JSON(http.StatusForbidden, PermissionDenied("Method '%s' is not allowed", method))
+			return
+		}
+		for _, requested := range strings.Split(ctx.Request.Header.Get("Access-Control-Request-Headers"), ",") {
+			requested = http.CanonicalHeaderKey(strings.TrimSpace(requested))
+			if requested != "" && !allowedHeaders[requested] {
+				ctx.JSON(http.StatusForbidden, PermissionDenied("Header '%s' is not allowed", requested))
+				return
+			}
+		}
+
+		policy.setOriginHeaders(ctx, origin)
+		header := ctx.Writer.Header()
+		header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		header.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		if policy.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge/time.Second)))
+		}
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+// Registers the preflight handlers of every path that has no OPTIONS
+// endpoint of its own. The paths that only differ by the names of their
+// wildcards share their preflight handler
+func (router *Router) servePreflights(engine *gin.Engine) {
+	methods := map[string][]string{}
+	headers := map[string]map[string]bool{}
+	options := map[string]bool{}
+	paths := []string{}
+	for _, ep := range router.server.Endpoints() {
+		path := preflightPath(fullPath(router.server, ep))
+		if _, found := methods[path]; !found {
+			paths = append(paths, path)
+			headers[path] = router.corsHeaders()
+		}
+		methods[path] = append(methods[path], ep.Method)
+		options[path] = options[path] || ep.Method == "OPTIONS"
+		for _, header := range endpointHeaders(ep) {
+			headers[path][http.CanonicalHeaderKey(header)] = true
+		}
+	}
+
+	registered := []string{}
+	for _, path := range paths {
+		if options[path] {
+			continue
+		}
+		if conflict := preflightConflict(registered, path); conflict != "" {
+			router.logger().Warn("Skipping CORS preflight", "path", path, "conflict", conflict)
+			continue
+		}
+		registered = append(registered, path)
+		sort.Strings(methods[path])
+		names := []string{}
+		for header := range headers[path] {
+			names = append(names, header)
+		}
+		sort.Strings(names)
+		engine.OPTIONS(path, router.CORS.preflight(methods[path], names))
+	}
+}
+
+// Names the wildcards of the path after their position, which gives the
+// same path to the paths of the same shape
+func preflightPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if isWildcard(segment) {
+			segments[i] = segment[:1] + "param" + strconv.Itoa(i)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Gin still cannot route paths of different methods that part ways at a
+// wildcard, like /files/*path and /files/:id/meta, under the same OPTIONS
+func preflightConflict(registered []string, path string) string {
+	for _, other := range registered {
+		if _, _, found := wildcardConflict(path, other); found {
+			return other
+		}
+	}
+	return ""
+}
+
+func (router *Router) corsHeaders() map[string]bool {
+	headers := map[string]bool{"Content-Type": true}
+	if len(router.Authenticators) != 0 {
+		headers["Authorization"] = true
+	}
+	for _, header := range router.CORS.AllowedHeaders {
+		headers[http.CanonicalHeaderKey(header)] = true
+	}
+	return headers
+}
+
+// The headers bound to the input of the endpoint
+func endpointHeaders(ep *Endpoint) []string {
+	headers := []string{}
+	for header := range ep.Headers {
+		headers = append(headers, header)
+	}
+	for _, directives := range binding.Directives(ep.InputType) {
+		for _, directive := range directives {
+			if tagkey, tagval, err := binding.ParseDirective(directive); err == nil && tagkey == "header" {
+				headers = append(headers, tagval)
+			}
+		}
+	}
+	return headers
+}
@@ -0,0 +1,160 @@
+package hermes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type BrowserService struct{}
+
+type Comment struct {
+	Session string `json:"-" hermes:"header=X-Session"`
+	Text    string
+}
+
+func (_ BrowserService) SNI() string { return "UNUSED" }
+
+func (_ BrowserService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("List", "GET", "/comments", nil, nil).Header("X-Page", "Page"),
+		hermes.EP("Post", "POST", "/comments", Comment{}, nil),
+		hermes.EP("Delete", "DELETE", "/comments/:id", nil, nil),
+	}
+}
+
+func (_ BrowserService) List(ctx context.Context) error              { return nil }
+func (_ BrowserService) Post(ctx context.Context, in *Comment) error { return nil }
+func (_ BrowserService) Delete(ctx context.Context) error            { return nil }
+
+func TestCORS(t *testing.T) {
+	router := hermes.NewRouter(BrowserService{})
+	router.CORS = &hermes.CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedHeaders:   []string{"X-Client-Version"},
+		ExposedHeaders:   []string{"Hermes-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	engine := gin.New()
+	require.NoError(t, router.Serve(engine))
+
+	preflight := func(origin, path, method, headers string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("OPTIONS", path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", headers)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := preflight("https://app.example.com", "/comments", "POST", "content-type, x-session")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, X-Client-Version, X-Page, X-Session", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = preflight("https://app.example.com", "/comments/1", "DELETE", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "DELETE", w.Header().Get("Access-Control-Allow-Methods"))
+
+	assert.Equal(t, http.StatusForbidden, preflight("https://evil.example.com", "/comments", "GET", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("https://app.example.com", "/comments", "PUT", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("https://app.example.com", "/comments", "GET", "X-Unknown").Code)
+
+	// Actual requests get the headers of allowed origins only
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/comments", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Hermes-Request-ID", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/comments", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	w = preflight("https://evil.example.com", "/comments", "GET", "")
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+}
+
+func TestCORSCredentialsFromAnyOrigin(t *testing.T) {
+	router := hermes.NewRouter(BrowserService{})
+	router.CORS = &hermes.CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	assert.Error(t, router.Serve(gin.New()))
+
+	router.CORS.AllowCredentials = false
+	engine := gin.New()
+	require.NoError(t, router.Serve(engine))
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/comments", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, "https://any.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+type UsersService struct{}
+
+func (_ UsersService) SNI() string { return "UNUSED" }
+
+func (_ UsersService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Get", "GET", "/users/:id", nil, nil),
+		hermes.EP("Delete", "DELETE", "/users/:uid", nil, nil),
+		hermes.EP("Posts", "POST", "/users/:user/posts", nil, nil),
+		hermes.EP("File", "GET", "/files/*path", nil, nil),
+		hermes.EP("Meta", "PUT", "/files/:id/meta", nil, nil),
+	}
+}
+
+func (_ UsersService) Get(ctx context.Context) error    { return nil }
+func (_ UsersService) Delete(ctx context.Context) error { return nil }
+func (_ UsersService) Posts(ctx context.Context) error  { return nil }
+func (_ UsersService) File(ctx context.Context) error   { return nil }
+func (_ UsersService) Meta(ctx context.Context) error   { return nil }
+
+func TestCORSWildcardNames(t *testing.T) {
+	router := hermes.NewRouter(UsersService{})
+	router.CORS = &hermes.CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}}
+	engine := gin.New()
+	require.NoError(t, router.Serve(engine))
+
+	preflight := func(path, method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("OPTIONS", path, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", method)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// Paths that only differ by the names of their wildcards share their preflight
+	w := preflight("/users/1", "DELETE")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "DELETE, GET", w.Header().Get("Access-Control-Allow-Methods"))
+
+	w = preflight("/users/1/posts", "POST")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "POST", w.Header().Get("Access-Control-Allow-Methods"))
+
+	// Gin cannot route both of these under OPTIONS, the first one wins
+	w = preflight("/files/a/b", "GET")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET", w.Header().Get("Access-Control-Allow-Methods"))
+}
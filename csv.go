@@ -0,0 +1,181 @@
+package hermes
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Encodes slice outputs as CSV, one row per element. Struct elements get a
+// column per exported field, named after its json tag; other elements are
+// written in a single "value" column. Nested values are written as JSON
+type CSVEncoder struct{}
+
+func (_ CSVEncoder) MediaType() string { return "text/csv" }
+
+func (_ CSVEncoder) CanEncode(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+}
+
+type csvColumn struct {
+	name  string
+	index int
+}
+
+func csvColumns(elemType reflect.Type) []csvColumn {
+	if elemType.Kind() != reflect.Struct {
+		return []csvColumn{{"value", -1}}
+	}
+
+	columns := []csvColumn{}
+	for i := 0; i < elemType.NumField(); i++ {
+		field := elemType.Field(i)
+		if field.PkgPath != "" { // Unexported
+			continue
+		}
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		columns = append(columns, csvColumn{name, i})
+	}
+	return columns
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func (enc CSVEncoder) Encode(w io.Writer, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !enc.CanEncode(rv.Type()) {
+		return fmt.Errorf("Cannot encode %T as CSV", v)
+	}
+
+	columns := csvColumns(derefType(rv.Type().Elem()))
+	writer := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		elem := rv.Index(i)
+		for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+
+		row := make([]string, len(columns))
+		for j, column := range columns {
+			cell := elem
+			if column.index >= 0 && elem.Kind() == reflect.Struct {
+				cell = elem.Field(column.index)
+			}
+			content, err := csvCell(cell)
+			if err != nil {
+				return fmt.Errorf("Failed to encode column %s: %v", column.name, err)
+			}
+			row[j] = content
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvCell(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("%v", v.Interface()), nil
+	}
+	content, err := json.Marshal(v.Interface())
+	return string(content), err
+}
+
+func (_ CSVEncoder) Decode(r io.Reader, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("Cannot decode CSV into %T", v)
+	}
+
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	} else if len(records) == 0 {
+		return nil
+	}
+
+	sliceType := rv.Elem().Type()
+	elemType := derefType(sliceType.Elem())
+	columns := map[string]int{}
+	for _, column := range csvColumns(elemType) {
+		columns[strings.ToLower(column.name)] = column.index
+	}
+
+	slice := reflect.MakeSlice(sliceType, 0, len(records)-1)
+	for _, record := range records[1:] {
+		elem := reflect.New(elemType)
+		for i, name := range records[0] {
+			index, found := columns[strings.ToLower(name)]
+			if !found || i >= len(record) || record[i] == "" {
+				continue
+			}
+			target := elem.Elem()
+			if index >= 0 && elemType.Kind() == reflect.Struct {
+				target = target.Field(index)
+			}
+			if err := parseCSVCell(target, record[i]); err != nil {
+				return fmt.Errorf("Failed to decode column %s: %v", name, err)
+			}
+		}
+
+		// Restore the pointers of the element type
+		value := elem.Elem()
+		for t := sliceType.Elem(); t.Kind() == reflect.Ptr; t = t.Elem() {
+			ptr := reflect.New(value.Type())
+			ptr.Elem().Set(value)
+			value = ptr
+		}
+		slice = reflect.Append(slice, value)
+	}
+	rv.Elem().Set(slice)
+	return nil
+}
+
+func parseCSVCell(target reflect.Value, cell string) error {
+	if derefType(target.Type()).Kind() == reflect.String {
+		cell = strconv.Quote(cell)
+	}
+	return json.Unmarshal([]byte(cell), target.Addr().Interface())
+}
@@ -0,0 +1,233 @@
+package hermes
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Encodes the outputs of endpoints into response bodies, and decodes them
+// on the client side. The media type is matched against the Accept header
+// of requests, and sent back as the Content-Type of responses
+type Encoder interface {
+	MediaType() string
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// Encoders can implement this interface if they only support some types
+type TypedEncoder interface {
+	CanEncode(t reflect.Type) bool
+}
+
+// The first encoder is used when the request does not ask for any format
+// in particular, or asks for a format that cannot encode the output
+var DefaultEncoders = []Encoder{
+	JSONEncoder{},
+	PrettyJSONEncoder{},
+	XMLEncoder{},
+	MsgPackEncoder{},
+	CSVEncoder{},
+}
+
+type JSONEncoder struct{}
+
+func (_ JSONEncoder) MediaType() string { return "application/json" }
+
+func (_ JSONEncoder) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (_ JSONEncoder) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// Selected with "Accept: application/json; pretty=true"
+type PrettyJSONEncoder struct{}
+
+func (_ PrettyJSONEncoder) MediaType() string { return "application/json; pretty=true" }
+
+func (_ PrettyJSONEncoder) Encode(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func (_ PrettyJSONEncoder) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+type XMLEncoder struct{}
+
+func (_ XMLEncoder) MediaType() string { return "application/xml" }
+
+func (_ XMLEncoder) Encode(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (_ XMLEncoder) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// XML documents have a single root, and encoding/xml does not support maps
+func (_ XMLEncoder) CanEncode(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Interface:
+		return false
+	}
+	return xmlEncodable(t, map[reflect.Type]bool{})
+}
+
+func xmlEncodable(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Map, reflect.Func, reflect.Chan:
+		return false
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath == "" && field.Tag.Get("xml") != "-" && !xmlEncodable(field.Type, seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type acceptEntry struct {
+	mediatype string
+	params    map[string]string
+	quality   float64
+}
+
+func parseAccept(accept string) []acceptEntry {
+	entries := []acceptEntry{}
+	for _, part := range strings.Split(accept, ",") {
+		mediatype, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, found := params["q"]; found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+			delete(params, "q")
+		}
+		if quality > 0 {
+			entries = append(entries, acceptEntry{mediatype, params, quality})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].quality > entries[j].quality })
+	return entries
+}
+
+func (entry acceptEntry) matches(mediatype string) bool {
+	if entry.mediatype == "*/*" || entry.mediatype == mediatype {
+		return true
+	}
+	return strings.HasSuffix(entry.mediatype, "/*") &&
+		strings.HasPrefix(mediatype, strings.TrimSuffix(entry.mediatype, "*"))
+}
+
+// Returns the encoder that best satisfies the Accept header for the given
+// output type, nil if none of them does
+func NegotiateEncoder(encoders []Encoder, accept string, t reflect.Type) Encoder {
+	if strings.TrimSpace(accept) == "" && len(encoders) != 0 {
+		return encoders[0]
+	}
+
+	for _, entry := range parseAccept(accept) {
+		var best Encoder
+		bestParams := -1
+		for _, encoder := range encoders {
+			mediatype, params, err := mime.ParseMediaType(encoder.MediaType())
+			if err != nil || !entry.matches(mediatype) || !hasParams(entry.params, params) {
+				continue
+			}
+			if typed, ok := encoder.(TypedEncoder); ok && t != nil && !typed.CanEncode(t) {
+				continue
+			}
+			// Wildcards select the encoders in order, specific media types
+			// select the encoder that matches the most parameters
+			if len(params) > bestParams && (best == nil || !strings.Contains(entry.mediatype, "*")) {
+				best, bestParams = encoder, len(params)
+			}
+		}
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
+
+func hasParams(params, required map[string]string) bool {
+	for key, value := range required {
+		if params[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the encoder that can decode a response with the given content
+// type, nil if none of them can
+func EncoderFor(encoders []Encoder, contenttype string) Encoder {
+	mediatype, _, err := mime.ParseMediaType(contenttype)
+	if err != nil {
+		return nil
+	}
+	for _, encoder := range encoders {
+		if encmediatype, _, err := mime.ParseMediaType(encoder.MediaType()); err == nil && encmediatype == mediatype {
+			return encoder
+		}
+	}
+	return nil
+}
+
+// Writes the output of a handler in the format negotiated with the client,
+// compressed if it is larger than the threshold and the client accepts it
+func writeOutput(ctx *gin.Context, encoders []Encoder, threshold int, code int, output interface{}) {
+	encoder := NegotiateEncoder(encoders, ctx.Request.Header.Get("Accept"), reflect.TypeOf(output))
+	if encoder == nil {
+		encoder = JSONEncoder{}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := encoder.Encode(buf, output); err != nil {
+		ctx.JSON(http.StatusInternalServerError, Internal("Failed to encode response as %s: %v", encoder.MediaType(), err))
+		return
+	}
+	content := buf.Bytes()
+	ctx.Header("Content-Type", encoder.MediaType())
+	ctx.Writer.Header().Add("Vary", "Accept")
+	if threshold > 0 && len(content) >= threshold {
+		ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateCompression(ctx.Request.Header.Get("Accept-Encoding"))
+		if compressed, ok := compress(encoding, content); ok {
+			content = compressed
+			ctx.Header("Content-Encoding", encoding)
+		}
+	}
+	ctx.Writer.WriteHeader(code)
+	ctx.Writer.Write(content)
+}
@@ -0,0 +1,164 @@
+package hermes_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ReportService struct{}
+
+type Row struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Ratio *float64 `json:"ratio"`
+	Tags  []string `json:"tags"`
+}
+
+type Summary struct {
+	Name  string
+	Total int64
+	Big   uint64
+	Score float64
+	Nil   *string
+	Rows  []Row
+	Extra map[string]bool
+}
+
+func (_ ReportService) SNI() string { return "UNUSED" }
+
+func (_ ReportService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Rows", "GET", "/rows", nil, []Row{}),
+		hermes.EP("Summary", "GET", "/summary", nil, Summary{}),
+		hermes.EP("First", "GET", "/first", nil, Row{}),
+	}
+}
+
+var half = 0.5
+
+var rows = []Row{
+	{"a", 1, &half, []string{"x", "y"}},
+	{"b, \"quoted\"", -300, nil, nil},
+}
+
+var summary = Summary{
+	Name:  "summary",
+	Total: -70000,
+	Big:   1 << 40,
+	Score: 3.25,
+	Rows:  rows,
+	Extra: map[string]bool{"t": true, "f": false},
+}
+
+func (_ ReportService) Rows(ctx context.Context, out *[]Row) (int, error) {
+	*out = rows
+	return http.StatusOK, nil
+}
+
+func (_ ReportService) Summary(ctx context.Context, out *Summary) (int, error) {
+	*out = summary
+	return http.StatusOK, nil
+}
+
+func (_ ReportService) First(ctx context.Context, out *Row) (int, error) {
+	*out = rows[0]
+	return http.StatusOK, nil
+}
+
+func reportEngine(t *testing.T) *gin.Engine {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(ReportService{}).Serve(engine))
+	return engine
+}
+
+func TestEncoderRoundTrips(t *testing.T) {
+	caller := hermes.NewCaller(ReportService{})
+	caller.Client = &hermes.MockClient{reportEngine(t)}
+
+	for _, accept := range []string{"application/json", "application/json; pretty=true", "application/msgpack", "text/csv"} {
+		caller.Accept = accept
+		out := []Row{}
+		_, err := caller.Call(context.Background(), "Rows", nil, &out)
+		assert.NoError(t, err, accept)
+		assert.Equal(t, rows, out, accept)
+	}
+
+	for _, accept := range []string{"application/json", "application/msgpack", "application/xml", "text/csv"} {
+		caller.Accept = accept
+		out := Summary{}
+		_, err := caller.Call(context.Background(), "Summary", nil, &out)
+		assert.NoError(t, err, accept)
+		assert.Equal(t, summary, out, accept)
+
+		first := Row{}
+		_, err = caller.Call(context.Background(), "First", nil, &first)
+		assert.NoError(t, err, accept)
+		assert.Equal(t, rows[0], first, accept)
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	engine := reportEngine(t)
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/rows", "text/csv")
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, "name,count,ratio,tags\na,1,0.5,\"[\"\"x\"\",\"\"y\"\"]\"\n\"b, \"\"quoted\"\"\",-300,,null\n", w.Body.String())
+
+	// CSV cannot encode structs, XML cannot encode slices or maps
+	assert.Equal(t, "application/json", get("/summary", "text/csv").Header().Get("Content-Type"))
+	assert.Equal(t, "application/json", get("/rows", "application/xml").Header().Get("Content-Type"))
+	assert.Equal(t, "text/csv", get("/rows", "application/xml, text/csv;q=0.5").Header().Get("Content-Type"))
+	assert.Equal(t, "application/xml", get("/first", "text/csv, application/*;q=0.2, application/xml;q=0.5").Header().Get("Content-Type"))
+	assert.Equal(t, "application/json", get("/summary", "application/xml, application/*;q=0.2").Header().Get("Content-Type"))
+	assert.Equal(t, "application/json", get("/summary", "*/*").Header().Get("Content-Type"))
+	assert.Equal(t, "application/json", get("/summary", "").Header().Get("Content-Type"))
+
+	pretty := get("/summary", "application/json; pretty=true")
+	assert.Equal(t, "application/json; pretty=true", pretty.Header().Get("Content-Type"))
+	assert.Contains(t, pretty.Body.String(), "\n  \"Name\": \"summary\",\n")
+}
+
+func TestNegotiateEncoder(t *testing.T) {
+	rowsType := reflect.TypeOf(rows)
+	encoders := hermes.DefaultEncoders
+	assert.Equal(t, hermes.CSVEncoder{}, hermes.NegotiateEncoder(encoders, "text/*", rowsType))
+	assert.Equal(t, hermes.MsgPackEncoder{}, hermes.NegotiateEncoder(encoders, "application/msgpack;q=0.9, text/csv;q=0.1", rowsType))
+	assert.Nil(t, hermes.NegotiateEncoder(encoders, "image/png", rowsType))
+	assert.Nil(t, hermes.NegotiateEncoder(encoders, "application/json;q=0", rowsType))
+}
+
+func TestMsgPackFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, hermes.MsgPackEncoder{}.Encode(buf, map[string]interface{}{"b": []int{1, -1}, "a": "x"}))
+	assert.Equal(t, []byte{0x82, 0xa1, 'a', 0xa1, 'x', 0xa1, 'b', 0x92, 0x01, 0xff}, buf.Bytes())
+}
+
+func TestMsgPackHostileLengths(t *testing.T) {
+	for _, content := range [][]byte{
+		{0xdb, 0xff, 0xff, 0xff, 0xff, 'a'},       // 4GiB string
+		{0xc6, 0xff, 0xff, 0xff, 0xff},            // 4GiB binary
+		{0xdd, 0xff, 0xff, 0xff, 0xff, 0x01},      // 4 billion items array
+		{0xdf, 0xff, 0xff, 0xff, 0xff, 0xa1, 'a'}, // 4 billion entries map
+		{0xda, 0x00, 0x10, 'a'},                   // Truncated string
+		bytes.Repeat([]byte{0x91}, 100000),        // Deeply nested arrays
+	} {
+		var out interface{}
+		assert.Error(t, hermes.MsgPackEncoder{}.Decode(bytes.NewReader(content), &out), "%x", content)
+	}
+}
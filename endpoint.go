@@ -1,6 +1,9 @@
 package hermes
 
-import "reflect"
+import (
+	"reflect"
+	"time"
+)
 
 type Endpoint struct {
 	Handler    string
@@ -12,6 +15,12 @@ type Endpoint struct {
 	Params  []string
 	Queries []string
 	Headers map[string]string
+
+	Interceptors   []Interceptor
+	HandlerTimeout time.Duration
+	Streaming      StreamFormat
+	AllowAnonymous bool
+	BodyLimit      int64
 }
 
 func NewEndpoint(handler, method, path string, input, output interface{}) *Endpoint {
@@ -45,3 +54,29 @@ func (ep *Endpoint) Header(varname, fieldname string) *Endpoint {
 	ep.Headers[varname] = fieldname
 	return ep
 }
+
+func (ep *Endpoint) Intercept(interceptors ...Interceptor) *Endpoint {
+	ep.Interceptors = append(ep.Interceptors, interceptors...)
+	return ep
+}
+
+// The context passed to the handler gets cancelled after the timeout, and
+// the request fails with a 504 if the handler has not returned by then
+func (ep *Endpoint) Timeout(timeout time.Duration) *Endpoint {
+	ep.HandlerTimeout = timeout
+	return ep
+}
+
+// Makes the endpoint stream its output. The handler receives a function
+// that sends one item at a time instead of an output pointer:
+// func (ctx context.Context, in *InputType, send func(*OutputType) error) (int, error)
+func (ep *Endpoint) Stream(format StreamFormat) *Endpoint {
+	ep.Streaming = format
+	return ep
+}
+
+// Lets requests to the endpoint through without authentication
+func (ep *Endpoint) Public() *Endpoint {
+	ep.AllowAnonymous = true
+	return ep
+}
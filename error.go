@@ -2,28 +2,207 @@ package hermes
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
-	"github.com/golang/glog"
+	"github.com/apourchet/hermes/binding"
+	"github.com/gin-gonic/gin"
 )
 
+// The errors returned by handlers are sent to callers as an Error. The
+// status of typed errors takes precedence over the code the handler returned
 type Error struct {
-	Message string
+	Code       string `json:",omitempty"`
+	Message    string
+	Details    map[string]interface{} `json:",omitempty"`
+	Fields     []binding.FieldError   `json:",omitempty"`
+	RetryAfter int                    `json:",omitempty"` // In seconds
+
+	// The HTTP status of the error, set by callers from the response
+	Status int `json:"-"`
+
+	cause error
 }
 
 func (e *Error) Error() string {
 	return e.Message
 }
 
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Errors with the same code match through errors.Is, e.g
+// errors.Is(err, hermes.NotFound(""))
+func (e *Error) Is(target error) bool {
+	casted, ok := target.(*Error)
+	return ok && casted.Code != "" && casted.Code == e.Code
+}
+
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = map[string]interface{}{}
+	}
+	e.Details[key] = value
+	return e
+}
+
+func (e *Error) WithField(field, message string) *Error {
+	e.Fields = append(e.Fields, binding.FieldError{Field: field, Message: message})
+	return e
+}
+
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	e.RetryAfter = int((d + time.Second - 1) / time.Second)
+	return e
+}
+
+func (e *Error) WithCause(cause error) *Error {
+	e.cause = cause
+	return e
+}
+
+const (
+	CodeInvalidArgument    = "INVALID_ARGUMENT"
+	CodeUnauthenticated    = "UNAUTHENTICATED"
+	CodePermissionDenied   = "PERMISSION_DENIED"
+	CodeNotFound           = "NOT_FOUND"
+	CodeAlreadyExists      = "ALREADY_EXISTS"
+	CodeFailedPrecondition = "FAILED_PRECONDITION"
+	CodeResourceExhausted  = "RESOURCE_EXHAUSTED"
+	CodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"
+	CodeInternal           = "INTERNAL"
+	CodeUnimplemented      = "UNIMPLEMENTED"
+	CodeUnavailable        = "UNAVAILABLE"
+	CodeDeadlineExceeded   = "DEADLINE_EXCEEDED"
+)
+
+func NewError(status int, code string, format string, args ...interface{}) *Error {
+	return &Error{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func InvalidArgument(format string, args ...interface{}) *Error {
+	return NewError(http.StatusBadRequest, CodeInvalidArgument, format, args...)
+}
+
+func Unauthenticated(format string, args ...interface{}) *Error {
+	return NewError(http.StatusUnauthorized, CodeUnauthenticated, format, args...)
+}
+
+func PermissionDenied(format string, args ...interface{}) *Error {
+	return NewError(http.StatusForbidden, CodePermissionDenied, format, args...)
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+	return NewError(http.StatusNotFound, CodeNotFound, format, args...)
+}
+
+func AlreadyExists(format string, args ...interface{}) *Error {
+	return NewError(http.StatusConflict, CodeAlreadyExists, format, args...)
+}
+
+func FailedPrecondition(format string, args ...interface{}) *Error {
+	return NewError(http.StatusPreconditionFailed, CodeFailedPrecondition, format, args...)
+}
+
+func ResourceExhausted(format string, args ...interface{}) *Error {
+	return NewError(http.StatusTooManyRequests, CodeResourceExhausted, format, args...)
+}
+
+func PayloadTooLarge(format string, args ...interface{}) *Error {
+	return NewError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, format, args...)
+}
+
+func Internal(format string, args ...interface{}) *Error {
+	return NewError(http.StatusInternalServerError, CodeInternal, format, args...)
+}
+
+func Unimplemented(format string, args ...interface{}) *Error {
+	return NewError(http.StatusNotImplemented, CodeUnimplemented, format, args...)
+}
+
+func Unavailable(format string, args ...interface{}) *Error {
+	return NewError(http.StatusServiceUnavailable, CodeUnavailable, format, args...)
+}
+
+func DeadlineExceeded(format string, args ...interface{}) *Error {
+	return NewError(http.StatusGatewayTimeout, CodeDeadlineExceeded, format, args...)
+}
+
+type errorMapping struct {
+	target error
+	status int
+	code   string
+}
+
+var (
+	errorMappings     = []errorMapping{}
+	errorMappingsLock = sync.RWMutex{}
+)
+
+// Maps the errors matching target through errors.Is to a status and code.
+// When callers register the same target and code, the errors they receive
+// match the target as well
+func RegisterError(target error, status int, code string) {
+	errorMappingsLock.Lock()
+	defer errorMappingsLock.Unlock()
+	errorMappings = append(errorMappings, errorMapping{target, status, code})
+}
+
+// Converts the error returned by a handler into the Error sent to callers,
+// along with its status. Untyped errors keep the code of the handler
+func ToError(code int, err error) (int, *Error) {
+	typed := &Error{}
+	if errors.As(err, &typed) {
+		if typed.Status != 0 {
+			code = typed.Status
+		}
+		return code, typed
+	}
+
+	invalid := &binding.ValidationError{}
+	if errors.As(err, &invalid) {
+		return http.StatusBadRequest, &Error{Code: CodeInvalidArgument, Message: err.Error(), Fields: invalid.Fields}
+	}
+
+	errorMappingsLock.RLock()
+	defer errorMappingsLock.RUnlock()
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.target) {
+			return mapping.status, &Error{Status: mapping.status, Code: mapping.code, Message: err.Error(), cause: err}
+		}
+	}
+	return code, &Error{Status: code, Message: err.Error(), cause: err}
+}
+
+// Sends the error, its Retry-After as a header as well
+func writeError(ctx *gin.Context, code int, e *Error) {
+	if e.RetryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
+	ctx.JSON(code, e)
+}
+
 type ErrorHandler func(ctx context.Context, path string, code int, err error)
 
 type SuccessHandler func(ctx context.Context, path string, code int)
 
 var DefaultErrorHandler ErrorHandler = LogError
 
+// Logs with the logger of the request. Client errors are only warnings
 func LogError(ctx context.Context, path string, code int, err error) {
-	glog.Errorf("[%s] %s => %d | %v", GetRequestID(ctx), path, code, err)
+	logger := requestLogger(ctx, path)
+	if code/100 == 4 {
+		logger.Warn("Request failed", "status", code, "latency", elapsed(ctx), "error", err)
+		return
+	}
+	logger.Error("Request failed", "status", code, "latency", elapsed(ctx), "error", err)
 }
 
 var DefaultSuccessHandler SuccessHandler = func(ctx context.Context, path string, code int) {
-	glog.Infof("[%s] %s => %d", GetRequestID(ctx), path, code)
+	requestLogger(ctx, path).Info("Request served", "status", code, "latency", elapsed(ctx))
 }
@@ -0,0 +1,80 @@
+package hermes_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ErrNoAccount = errors.New("No such account")
+
+func init() {
+	hermes.RegisterError(ErrNoAccount, http.StatusNotFound, "NO_ACCOUNT")
+}
+
+type AccountsService struct{}
+
+func (_ AccountsService) SNI() string { return "UNUSED" }
+
+func (_ AccountsService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Get", "GET", "/accounts/:action", Action{}, nil).Param("action"),
+	}
+}
+
+func (_ AccountsService) Get(ctx context.Context, in *Action) (int, error) {
+	switch in.Action {
+	case 1:
+		return http.StatusInternalServerError, hermes.NotFound("Account %d not found", in.Action).WithDetail("id", "1")
+	case 2:
+		return http.StatusInternalServerError, fmt.Errorf("Failed to load account: %w", ErrNoAccount)
+	case 3:
+		return 0, hermes.ResourceExhausted("Too many requests").WithRetryAfter(1500 * time.Millisecond)
+	}
+	return http.StatusConflict, fmt.Errorf("Account is locked")
+}
+
+func TestStructuredErrors(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(AccountsService{}).Serve(engine))
+	caller := hermes.NewCaller(AccountsService{})
+	caller.Client = &hermes.MockClient{engine}
+
+	// Typed errors override the status returned by the handler
+	code, err := caller.Call(context.Background(), "Get", &Action{1}, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.True(t, errors.Is(err, hermes.NotFound("")))
+	typed := &hermes.Error{}
+	require.True(t, errors.As(err, &typed))
+	assert.Equal(t, hermes.CodeNotFound, typed.Code)
+	assert.Equal(t, "Account 1 not found", typed.Message)
+	assert.Equal(t, map[string]interface{}{"id": "1"}, typed.Details)
+	assert.Equal(t, http.StatusNotFound, typed.Status)
+
+	// Registered errors are mapped, and reconstructed by the caller
+	code, err = caller.Call(context.Background(), "Get", &Action{2}, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.True(t, errors.Is(err, ErrNoAccount))
+	assert.EqualError(t, err, "Failed to load account: No such account")
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/accounts/3", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"Code": "RESOURCE_EXHAUSTED", "Message": "Too many requests", "RetryAfter": 2}`, w.Body.String())
+
+	// Untyped errors keep the status returned by the handler
+	code, err = caller.Call(context.Background(), "Get", &Action{4}, nil)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.EqualError(t, err, "Account is locked")
+	assert.False(t, errors.Is(err, hermes.NotFound("")))
+}
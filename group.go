@@ -0,0 +1,40 @@
+package hermes
+
+import "strings"
+
+// Returns copies of the endpoints with their paths under the prefix. The
+// copies do not share anything with the endpoints, so that the builder
+// methods of one do not change the other
+func Group(prefix string, endpoints EndpointMap) EndpointMap {
+	grouped := make(EndpointMap, len(endpoints))
+	for i, ep := range endpoints {
+		cp := *ep
+		cp.Path = joinPaths(prefix, ep.Path)
+		cp.Params = append([]string(nil), ep.Params...)
+		cp.Queries = append([]string(nil), ep.Queries...)
+		cp.Interceptors = append([]Interceptor(nil), ep.Interceptors...)
+		cp.Headers = make(map[string]string, len(ep.Headers))
+		for varname, fieldname := range ep.Headers {
+			cp.Headers[varname] = fieldname
+		}
+		grouped[i] = &cp
+	}
+	return grouped
+}
+
+// Returns the path of the endpoint once mounted under the base path of
+// the server, if it has one
+func fullPath(server Server, ep *Endpoint) string {
+	if pather, ok := server.(BasePather); ok {
+		return joinPaths(pather.BasePath(), ep.Path)
+	}
+	return ep.Path
+}
+
+func joinPaths(base, path string) string {
+	base = strings.Trim(base, "/")
+	if base == "" {
+		return path
+	}
+	return "/" + base + "/" + strings.TrimLeft(path, "/")
+}
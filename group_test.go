@@ -0,0 +1,76 @@
+package hermes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type PaymentsService struct {
+	hermes.HealthChecker
+}
+
+func (_ PaymentsService) SNI() string { return "UNUSED" }
+
+func (_ PaymentsService) BasePath() string { return "/payments/v1/" }
+
+func (_ PaymentsService) Endpoints() hermes.EndpointMap {
+	admin := hermes.Group("/admin", hermes.EndpointMap{
+		hermes.EP("Refund", "POST", "/refunds/:action", Action{}, nil).Param("action"),
+	})
+	return append(admin, hermes.Healthz)
+}
+
+func (_ PaymentsService) Refund(ctx context.Context, in *Action) (int, error) {
+	if in.Action != 42 {
+		return http.StatusBadRequest, nil
+	}
+	return http.StatusCreated, nil
+}
+
+func TestBasePathAndGroups(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(PaymentsService{}).Serve(engine))
+
+	caller := hermes.NewCaller(PaymentsService{})
+	caller.Client = &hermes.MockClient{engine}
+	caller.Resolve = func(sni, path string) (string, error) {
+		assert.Contains(t, []string{"/payments/v1/admin/refunds/:action", "/payments/v1/hermes/healthz"}, path)
+		return sni + path, nil
+	}
+
+	code, err := caller.Call(context.Background(), "Refund", &Action{42}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, code)
+
+	code, err = caller.Call(context.Background(), "Healthz", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/payments/v1/admin/refunds/42", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// The shared endpoint was not modified by the group
+	assert.Equal(t, "/hermes/healthz", hermes.Healthz.Path)
+}
+
+func TestGroupCopiesEndpoints(t *testing.T) {
+	ep := hermes.EP("Refund", "POST", "/refunds/:a/:b/:c", nil, nil).Param("a", "b").Param("c").Query("q")
+	ep.Header("X-Refund", "Refund")
+	grouped := hermes.Group("/admin", hermes.EndpointMap{ep})[0]
+	grouped.Param("other").Query("other").Header("X-Other", "Other")
+	ep.Param("more").Header("X-More", "More")
+
+	assert.Equal(t, []string{"a", "b", "c", "more"}, ep.Params)
+	assert.Equal(t, []string{"a", "b", "c", "other"}, grouped.Params)
+	assert.Equal(t, []string{"q"}, ep.Queries)
+	assert.Equal(t, map[string]string{"X-Refund": "Refund", "X-More": "More"}, ep.Headers)
+	assert.Equal(t, map[string]string{"X-Refund": "Refund", "X-Other": "Other"}, grouped.Headers)
+}
@@ -0,0 +1,59 @@
+package hermes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/", hermes.NewRouter(&MyService{}).Handler())
+	mux.Handle("/payments/v1/", hermes.NewRouter(PaymentsService{}).Handler())
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resolve := func(_, path string) (string, error) {
+		return strings.TrimPrefix(server.URL, "http://") + path, nil
+	}
+	caller := hermes.NewCaller(&MyService{})
+	caller.Resolve = resolve
+
+	out := &Outbound{}
+	_, err := caller.Call(context.Background(), "RpcCall", &Inbound{"secret"}, out)
+	assert.NoError(t, err)
+	assert.True(t, out.Ok)
+
+	_, err = caller.Call(context.Background(), "Paramed", &Action{69}, nil)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest("GET", server.URL+"/tagged/mypath?q1=42", nil)
+	require.NoError(t, err)
+	req.Header.Add("h1", "myheader")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	payments := hermes.NewCaller(PaymentsService{})
+	payments.Resolve = resolve
+	code, err := payments.Call(context.Background(), "Refund", &Action{42}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, code)
+
+	resp, err = http.Get(server.URL + "/nothing/here")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+}
+
+func TestRouterHandlerPanicsOnInvalidEndpointMap(t *testing.T) {
+	assert.Panics(t, func() { hermes.NewRouter(BrokenService{}).Handler() })
+}
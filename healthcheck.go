@@ -7,6 +7,12 @@ import (
 
 type HealthChecker struct{}
 
-func (svc HealthChecker) Healthz(context.Context) (int, error) { return http.StatusOK, nil }
+// Reports 503 once the server started shutting down
+func (svc HealthChecker) Healthz(ctx context.Context) (int, error) {
+	if Draining(ctx) {
+		return http.StatusServiceUnavailable, Unavailable("Server is shutting down")
+	}
+	return http.StatusOK, nil
+}
 
-var Healthz = NewEndpoint("Healthz", "GET", "/hermes/healthz", nil, nil)
+var Healthz = NewEndpoint("Healthz", "GET", "/hermes/healthz", nil, nil).Public()
@@ -0,0 +1,89 @@
+package hermes
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A single call to the handler of an endpoint. Input and Output are the
+// pointers that get passed to the handler, nil if the endpoint has no
+// input or output type
+type Invocation struct {
+	Context  context.Context
+	Gin      *gin.Context
+	Endpoint *Endpoint
+	Input    interface{}
+	Output   interface{}
+}
+
+type Invoker func(inv *Invocation) (int, error)
+
+// Wraps the call to a handler. An interceptor can inspect or modify the
+// invocation, then either call next or short-circuit the handler by
+// returning a code and an error directly
+type Interceptor func(inv *Invocation, next Invoker) (int, error)
+
+func chainInterceptors(interceptors []Interceptor, final Invoker) Invoker {
+	invoke := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoke
+		invoke = func(inv *Invocation) (int, error) {
+			return interceptor(inv, next)
+		}
+	}
+	return invoke
+}
+
+// Calls the method of the service with the arguments of the invocation.
+// Handlers that return their output get it copied into the output pointer
+func methodInvoker(svc Server, method reflect.Method, shape handlerShape) Invoker {
+	return func(inv *Invocation) (int, error) {
+		args := []reflect.Value{reflect.ValueOf(svc)}
+		if method.Type.In(1) == ginContextType {
+			args = append(args, reflect.ValueOf(inv.Gin))
+		} else {
+			args = append(args, reflect.ValueOf(inv.Context))
+		}
+		if inv.Input != nil {
+			args = append(args, reflect.ValueOf(inv.Input))
+		}
+		if inv.Output != nil && shape != outputShape {
+			args = append(args, reflect.ValueOf(inv.Output))
+		}
+
+		vals := method.Func.Call(args)
+		switch shape {
+		case errorShape:
+			if !vals[0].IsNil() {
+				return inferStatus(vals[0].Interface().(error))
+			} else if inv.Endpoint.Streaming != "" {
+				return http.StatusOK, nil
+			}
+			return http.StatusNoContent, nil
+		case outputShape:
+			if !vals[1].IsNil() {
+				return inferStatus(vals[1].Interface().(error))
+			} else if vals[0].IsNil() {
+				inv.Output = nil
+				return http.StatusNoContent, nil
+			}
+			reflect.ValueOf(inv.Output).Elem().Set(vals[0].Elem())
+			return http.StatusOK, nil
+		}
+
+		code := int(vals[0].Int())
+		if vals[1].IsNil() {
+			return code, nil
+		}
+		return code, vals[1].Interface().(error)
+	}
+}
+
+// Errors that are neither typed nor registered are internal errors
+func inferStatus(err error) (int, error) {
+	code, _ := ToError(http.StatusInternalServerError, err)
+	return code, err
+}
@@ -0,0 +1,91 @@
+package hermes_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type InterceptedService struct{}
+
+func (_ InterceptedService) SNI() string { return "UNUSED" }
+
+func (_ InterceptedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Echo", "POST", "/echo", Inbound{}, Outbound{}),
+		hermes.EP("Guarded", "POST", "/guarded", Inbound{}, Outbound{}).Intercept(guard),
+	}
+}
+
+func (_ InterceptedService) Echo(ctx context.Context, in *Inbound, out *Outbound) (int, error) {
+	out.Ok = in.Message == "secret"
+	return http.StatusOK, nil
+}
+
+func (_ InterceptedService) Guarded(ctx context.Context, in *Inbound, out *Outbound) (int, error) {
+	out.Ok = true
+	return http.StatusOK, nil
+}
+
+func guard(inv *hermes.Invocation, next hermes.Invoker) (int, error) {
+	if inv.Input.(*Inbound).Message != "let me in" {
+		return http.StatusForbidden, fmt.Errorf("Forbidden")
+	}
+	return next(inv)
+}
+
+func TestInterceptors(t *testing.T) {
+	type record struct {
+		handler string
+		message string
+		ok      bool
+		code    int
+	}
+	records := []record{}
+	audit := func(inv *hermes.Invocation, next hermes.Invoker) (int, error) {
+		code, err := next(inv)
+		records = append(records, record{
+			handler: inv.Endpoint.Handler,
+			message: inv.Input.(*Inbound).Message,
+			ok:      inv.Output.(*Outbound).Ok,
+			code:    code,
+		})
+		return code, err
+	}
+
+	engine := gin.New()
+	err := hermes.NewRouter(InterceptedService{}).Intercept(audit).Serve(engine)
+	require.NoError(t, err)
+
+	caller := hermes.NewCaller(InterceptedService{})
+	caller.Client = &hermes.MockClient{engine}
+
+	out := &Outbound{}
+	code, err := caller.Call(context.Background(), "Echo", &Inbound{"secret"}, out)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, out.Ok)
+
+	out = &Outbound{}
+	code, err = caller.Call(context.Background(), "Guarded", &Inbound{"knock knock"}, out)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.False(t, out.Ok)
+
+	code, err = caller.Call(context.Background(), "Guarded", &Inbound{"let me in"}, out)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, out.Ok)
+
+	assert.Equal(t, []record{
+		{"Echo", "secret", true, http.StatusOK},
+		{"Guarded", "knock knock", false, http.StatusForbidden},
+		{"Guarded", "let me in", true, http.StatusOK},
+	}, records)
+}
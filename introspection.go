@@ -0,0 +1,139 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/apourchet/hermes/binding"
+)
+
+// Lists the endpoints of the service it is embedded in, once
+// hermes.Introspection is added to its EndpointMap
+type Introspector struct{}
+
+func (_ Introspector) Introspect(ctx context.Context, out *[]EndpointDescription) (int, error) {
+	server, ok := ctx.Value(serverKey).(Server)
+	if !ok {
+		return http.StatusInternalServerError, fmt.Errorf("No server to introspect")
+	}
+	*out = DescribeEndpoints(server)
+	return http.StatusOK, nil
+}
+
+var Introspection = NewEndpoint("Introspect", "GET", "/hermes/endpoints", nil, []EndpointDescription{})
+
+// The Router makes the served server available in the context of requests
+const serverKey = "Hermes-Server"
+
+type EndpointDescription struct {
+	Handler   string
+	Method    string
+	Path      string
+	Params    []string
+	Queries   []string
+	Headers   map[string]string
+	Streaming StreamFormat     `json:",omitempty"`
+	Public    bool             `json:",omitempty"`
+	Input     *TypeDescription `json:",omitempty"`
+	Output    *TypeDescription `json:",omitempty"`
+}
+
+type TypeDescription struct {
+	Name   string `json:",omitempty"`
+	Kind   string
+	Key    *TypeDescription   `json:",omitempty"`
+	Elem   *TypeDescription   `json:",omitempty"`
+	Fields []FieldDescription `json:",omitempty"`
+}
+
+type FieldDescription struct {
+	Name   string
+	JSON   string
+	Hermes []string `json:",omitempty"`
+	Type   *TypeDescription
+}
+
+func DescribeEndpoints(server Server) []EndpointDescription {
+	descriptions := []EndpointDescription{}
+	for _, ep := range server.Endpoints() {
+		desc := EndpointDescription{
+			Handler:   ep.Handler,
+			Method:    ep.Method,
+			Path:      fullPath(server, ep),
+			Params:    append([]string{}, ep.Params...),
+			Queries:   append([]string{}, ep.Queries...),
+			Headers:   ep.Headers,
+			Streaming: ep.Streaming,
+			Public:    ep.AllowAnonymous,
+		}
+		if ep.InputType != nil {
+			desc.Input = DescribeType(ep.InputType)
+		}
+		if ep.OutputType != nil {
+			desc.Output = DescribeType(ep.OutputType)
+		}
+		descriptions = append(descriptions, desc)
+	}
+	return descriptions
+}
+
+func DescribeType(t reflect.Type) *TypeDescription {
+	return describeType(t, map[reflect.Type]bool{})
+}
+
+// Recursive types are only described once, further occurrences only have
+// their name and kind
+func describeType(t reflect.Type, describing map[reflect.Type]bool) *TypeDescription {
+	desc := &TypeDescription{Name: t.String(), Kind: t.Kind().String()}
+	if t.Name() == "" {
+		desc.Name = ""
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		desc.Elem = describeType(t.Elem(), describing)
+	case reflect.Map:
+		desc.Key = describeType(t.Key(), describing)
+		desc.Elem = describeType(t.Elem(), describing)
+	case reflect.Struct:
+		if describing[t] {
+			return desc
+		}
+		describing[t] = true
+		desc.Fields = describeFields(t, describing)
+		delete(describing, t)
+	}
+	return desc
+}
+
+func describeFields(t reflect.Type, describing map[reflect.Type]bool) []FieldDescription {
+	fields := []FieldDescription{}
+	directives := binding.Directives(t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" { // Unexported
+			continue
+		}
+
+		jsonname := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonname == "" {
+			// Embedded structs are flattened in JSON
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				fields = append(fields, describeFields(field.Type, describing)...)
+				continue
+			}
+			jsonname = field.Name
+		}
+
+		fields = append(fields, FieldDescription{
+			Name:   field.Name,
+			JSON:   jsonname,
+			Hermes: directives[field.Name],
+			Type:   describeType(field.Type, describing),
+		})
+	}
+	return fields
+}
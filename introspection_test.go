@@ -0,0 +1,87 @@
+package hermes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type IntrospectedService struct {
+	hermes.HealthChecker
+	hermes.Introspector
+}
+
+type Tree struct {
+	Value    int    `json:"value"`
+	Children []Tree `json:"children,omitempty"`
+}
+
+type Search struct {
+	Pagination
+	Token string `json:"-" hermes:"header=Authorization"`
+	Query string `hermes:"query=q"`
+}
+
+type Pagination struct {
+	Limit *int
+}
+
+func (_ IntrospectedService) SNI() string { return "UNUSED" }
+
+func (_ IntrospectedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Find", "GET", "/trees/:action", Search{}, map[string]Tree{}).Param("action").Header("X-Trace", "token"),
+		hermes.Healthz,
+		hermes.Introspection,
+	}
+}
+
+func (_ IntrospectedService) Find(ctx context.Context, in *Search, out *map[string]Tree) (int, error) {
+	return http.StatusOK, nil
+}
+
+func TestIntrospection(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(IntrospectedService{}).Serve(engine))
+	caller := hermes.NewCaller(IntrospectedService{})
+	caller.Client = &hermes.MockClient{engine}
+
+	out := []hermes.EndpointDescription{}
+	code, err := caller.Call(context.Background(), "Introspect", nil, &out)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, out, 3)
+
+	find := out[0]
+	assert.Equal(t, "Find", find.Handler)
+	assert.Equal(t, "GET", find.Method)
+	assert.Equal(t, "/trees/:action", find.Path)
+	assert.Equal(t, []string{"action"}, find.Params)
+	assert.Equal(t, map[string]string{"X-Trace": "token"}, find.Headers)
+
+	intType := &hermes.TypeDescription{Name: "int", Kind: "int"}
+	assert.Equal(t, &hermes.TypeDescription{
+		Name: "hermes_test.Search",
+		Kind: "struct",
+		Fields: []hermes.FieldDescription{
+			{Name: "Limit", JSON: "Limit", Type: &hermes.TypeDescription{Kind: "ptr", Elem: intType}},
+			{Name: "Token", JSON: "-", Hermes: []string{"header=Authorization"}, Type: &hermes.TypeDescription{Name: "string", Kind: "string"}},
+			{Name: "Query", JSON: "Query", Hermes: []string{"query=q"}, Type: &hermes.TypeDescription{Name: "string", Kind: "string"}},
+		},
+	}, find.Input)
+
+	tree := find.Output.Elem
+	assert.Equal(t, "map", find.Output.Kind)
+	assert.Equal(t, "hermes_test.Tree", tree.Name)
+	assert.Equal(t, "children", tree.Fields[1].JSON)
+	assert.Equal(t, &hermes.TypeDescription{Name: "hermes_test.Tree", Kind: "struct"}, tree.Fields[1].Type.Elem)
+
+	assert.Equal(t, "/hermes/healthz", out[1].Path)
+	assert.Equal(t, "/hermes/endpoints", out[2].Path)
+	assert.Equal(t, "slice", out[2].Output.Kind)
+}
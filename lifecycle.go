@@ -0,0 +1,142 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// The Router tells handlers whether the server is shutting down through
+// the context of requests
+const drainingKey = "Hermes-Draining"
+
+// Returns true once the server of the request started shutting down
+func Draining(ctx context.Context) bool {
+	draining, _ := ctx.Value(drainingKey).(bool)
+	return draining
+}
+
+var (
+	DefaultShutdownDelay = 5 * time.Second
+	DefaultDrainTimeout  = 30 * time.Second
+)
+
+// Serves a Router until the context is done or the process gets one of the
+// signals. It then shuts down gracefully: /hermes/healthz reports 503 for
+// ShutdownDelay so that load balancers stop sending requests, new
+// connections are refused, and requests in flight get DrainTimeout to
+// finish before their connections are closed
+type ManagedServer struct {
+	Server        *http.Server
+	Signals       []os.Signal
+	ShutdownDelay time.Duration
+	DrainTimeout  time.Duration
+
+	router   *Router
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// Writes are not limited in time if the router has streaming endpoints,
+// since streams can last for as long as they need. Fails if the
+// EndpointMap of the router is invalid
+func NewManagedServer(addr string, router *Router) (*ManagedServer, error) {
+	engine, err := router.engine()
+	if err != nil {
+		return nil, err
+	}
+
+	writeTimeout := 60 * time.Second
+	for _, ep := range router.server.Endpoints() {
+		if ep.Streaming != "" {
+			writeTimeout = 0
+		}
+	}
+
+	return &ManagedServer{
+		Server: &http.Server{
+			Addr:              addr,
+			Handler:           engine,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       60 * time.Second,
+			WriteTimeout:      writeTimeout,
+			IdleTimeout:       120 * time.Second,
+		},
+		Signals:       []os.Signal{syscall.SIGTERM, os.Interrupt},
+		ShutdownDelay: DefaultShutdownDelay,
+		DrainTimeout:  DefaultDrainTimeout,
+		router:        router,
+		stop:          make(chan struct{}),
+	}, nil
+}
+
+// Serves the router on the address until it is shut down
+func Serve(ctx context.Context, addr string, router *Router) error {
+	server, err := NewManagedServer(addr, router)
+	if err != nil {
+		return err
+	}
+	return server.ListenAndServe(ctx)
+}
+
+func (s *ManagedServer) ListenAndServe(ctx context.Context) error {
+	addr := s.Server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serves on the listener, and returns nil once shut down gracefully
+func (s *ManagedServer) Serve(ctx context.Context, listener net.Listener) error {
+	signals := make(chan os.Signal, 1)
+	if len(s.Signals) != 0 {
+		signal.Notify(signals, s.Signals...)
+		defer signal.Stop(signals)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- s.Server.Serve(listener) }()
+
+	select {
+	case err := <-served:
+		return err
+	case sig := <-signals:
+		s.router.logger().Info("Shutting down", "signal", sig)
+	case <-ctx.Done():
+	case <-s.stop:
+	}
+	return s.drain(served)
+}
+
+// Shuts the server down gracefully, like the signals do
+func (s *ManagedServer) Shutdown() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+func (s *ManagedServer) drain(served chan error) error {
+	atomic.StoreInt32(&s.router.draining, 1)
+	time.Sleep(s.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.DrainTimeout)
+	defer cancel()
+	if err := s.Server.Shutdown(ctx); err != nil {
+		s.Server.Close()
+		return fmt.Errorf("Requests still in flight after %v: %v", s.DrainTimeout, err)
+	}
+	if err := <-served; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
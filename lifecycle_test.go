@@ -0,0 +1,117 @@
+package hermes_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apourchet/hermes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type DrainService struct {
+	hermes.HealthChecker
+	started chan bool
+	release chan bool
+}
+
+func (_ DrainService) SNI() string { return "UNUSED" }
+
+func (_ DrainService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.Healthz,
+		hermes.EP("Slow", "GET", "/slow", nil, nil),
+	}
+}
+
+func (svc DrainService) Slow(ctx context.Context) (int, error) {
+	svc.started <- true
+	<-svc.release
+	return http.StatusOK, nil
+}
+
+func serveManaged(t *testing.T, svc DrainService, delay, timeout time.Duration) (*hermes.ManagedServer, string, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server, err := hermes.NewManagedServer("", hermes.NewRouter(svc))
+	require.NoError(t, err)
+	server.Signals = nil
+	server.ShutdownDelay = delay
+	server.DrainTimeout = timeout
+
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(context.Background(), listener) }()
+	return server, "http://" + listener.Addr().String(), done
+}
+
+func TestManagedServerDrains(t *testing.T) {
+	svc := DrainService{started: make(chan bool), release: make(chan bool)}
+	server, url, done := serveManaged(t, svc, 200*time.Millisecond, 5*time.Second)
+
+	resp, err := http.Get(url + "/hermes/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	slow := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			slow <- 0
+			return
+		}
+		resp.Body.Close()
+		slow <- resp.StatusCode
+	}()
+	<-svc.started
+
+	server.Shutdown()
+	time.Sleep(50 * time.Millisecond)
+	resp, err = http.Get(url + "/hermes/healthz")
+	require.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, string(body), hermes.CodeUnavailable)
+
+	// The request in flight finishes before the server stops
+	close(svc.release)
+	assert.Equal(t, http.StatusOK, <-slow)
+	assert.NoError(t, <-done)
+
+	_, err = http.Get(url + "/hermes/healthz")
+	assert.Error(t, err)
+}
+
+func TestManagedServerDrainTimeout(t *testing.T) {
+	svc := DrainService{started: make(chan bool), release: make(chan bool)}
+	defer close(svc.release)
+	server, url, done := serveManaged(t, svc, 0, 100*time.Millisecond)
+
+	go http.Get(url + "/slow")
+	<-svc.started
+	server.Shutdown()
+	assert.Error(t, <-done)
+}
+
+func TestServeStopsWithContext(t *testing.T) {
+	hermes.DefaultShutdownDelay = 0
+	defer func() { hermes.DefaultShutdownDelay = 5 * time.Second }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- hermes.Serve(ctx, "127.0.0.1:0", hermes.NewRouter(DrainService{})) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestServeInvalidEndpointMap(t *testing.T) {
+	err := hermes.Serve(context.Background(), "127.0.0.1:0", hermes.NewRouter(BrokenService{}))
+	require.Error(t, err)
+	assert.IsType(t, &hermes.EndpointMapError{}, err)
+}
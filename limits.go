@@ -0,0 +1,131 @@
+package hermes
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/apourchet/hermes/binding"
+)
+
+// Applies to routers that do not set their own limit, 0 disables it
+var DefaultMaxBodySize int64 = 10 << 20
+
+// Protects the decoding of JSON bodies from pathological inputs. Zero
+// values disable the limits
+type JSONLimits struct {
+	MaxDepth       int
+	MaxArrayLength int
+}
+
+var DefaultJSONLimits = JSONLimits{MaxDepth: 64, MaxArrayLength: 100000}
+
+// Fails reads with a 413 once more than limit bytes were read
+type limitedBody struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func (body *limitedBody) Read(p []byte) (int, error) {
+	if body.remaining < 0 {
+		return 0, body.tooLarge()
+	}
+	if int64(len(p)) > body.remaining+1 {
+		p = p[:body.remaining+1]
+	}
+	n, err := body.ReadCloser.Read(p)
+	if int64(n) > body.remaining {
+		n, body.remaining = int(body.remaining), -1
+		return n, body.tooLarge()
+	}
+	body.remaining -= int64(n)
+	return n, err
+}
+
+func (body *limitedBody) tooLarge() error {
+	return PayloadTooLarge("Request body is larger than %d bytes", body.limit)
+}
+
+func limitBody(req *http.Request, limit int64) error {
+	if limit <= 0 || req.Body == nil {
+		return nil
+	} else if req.ContentLength > limit {
+		return PayloadTooLarge("Request body is larger than %d bytes", limit)
+	}
+	req.Body = &limitedBody{req.Body, limit, limit}
+	return nil
+}
+
+// Decompresses the body of the request so that the limit applies to its
+// decoded content as well, then checks the complexity of its JSON
+func prepareBody(req *http.Request, limit int64, limits JSONLimits) error {
+	if req.Body == nil || req.ContentLength == 0 {
+		return nil
+	}
+	compressed := req.Header.Get("Content-Encoding") != ""
+	if compressed {
+		if err := binding.DecompressRequest(req); err != nil {
+			return InvalidArgument("%v", err)
+		}
+		if err := limitBody(req, limit); err != nil {
+			return err
+		}
+	}
+	checked := limits.MaxDepth > 0 || limits.MaxArrayLength > 0
+	if !compressed && !checked {
+		return nil
+	}
+
+	// The length of decompressed bodies is only known once they are read
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	if !checked {
+		return nil
+	}
+	return checkJSON(body, limits)
+}
+
+// Malformed JSON is left for the bindings to report
+func checkJSON(body []byte, limits JSONLimits) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	arrays := []bool{}
+	lengths := []int{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+
+		delim, isDelim := token.(json.Delim)
+		if isDelim && (delim == ']' || delim == '}') {
+			arrays, lengths = arrays[:len(arrays)-1], lengths[:len(lengths)-1]
+			continue
+		}
+		if depth := len(arrays); depth != 0 && arrays[depth-1] {
+			lengths[depth-1]++
+			if limits.MaxArrayLength > 0 && lengths[depth-1] > limits.MaxArrayLength {
+				return InvalidArgument("JSON arrays cannot have more than %d elements", limits.MaxArrayLength)
+			}
+		}
+		if isDelim {
+			arrays, lengths = append(arrays, delim == '['), append(lengths, 0)
+			if limits.MaxDepth > 0 && len(arrays) > limits.MaxDepth {
+				return InvalidArgument("JSON cannot be nested more than %d levels deep", limits.MaxDepth)
+			}
+		}
+	}
+}
+
+// Limits the size of the body of requests to the endpoint, decompressed or
+// not. Requests with larger bodies fail with a 413
+func (ep *Endpoint) MaxBodySize(limit int64) *Endpoint {
+	ep.BodyLimit = limit
+	return ep
+}
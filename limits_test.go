@@ -0,0 +1,92 @@
+package hermes_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type UploadService struct{}
+
+type Payload struct {
+	Data interface{}
+}
+
+func (_ UploadService) SNI() string { return "UNUSED" }
+
+func (_ UploadService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Small", "POST", "/small", Payload{}, nil).MaxBodySize(100),
+		hermes.EP("Large", "POST", "/large", Payload{}, nil),
+	}
+}
+
+func (_ UploadService) Small(ctx context.Context, in *Payload) error { return nil }
+
+func (_ UploadService) Large(ctx context.Context, in *Payload) error { return nil }
+
+func TestBodyLimits(t *testing.T) {
+	router := hermes.NewRouter(UploadService{})
+	router.DefaultMaxBodySize = 1000
+	router.JSONLimits = hermes.JSONLimits{MaxDepth: 4, MaxArrayLength: 10}
+	engine := gin.New()
+	require.NoError(t, router.Serve(engine))
+
+	post := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	body := func(path, content string) *http.Request {
+		return httptest.NewRequest("POST", path, strings.NewReader(content))
+	}
+	large := `{"Data": "` + strings.Repeat("a", 200) + `"}`
+
+	assert.Equal(t, http.StatusNoContent, post(body("/small", `{"Data": "a"}`)).Code)
+	w := post(body("/small", large))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), hermes.CodePayloadTooLarge)
+	assert.Equal(t, http.StatusNoContent, post(body("/large", large)).Code)
+
+	// Bodies of unknown length are cut off once over the limit
+	req := body("/small", large)
+	req.ContentLength = -1
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(req).Code)
+
+	// The limit applies to decompressed bodies as well
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	writer.Write([]byte(`{"Data": "` + strings.Repeat("a", 100000) + `"}`))
+	writer.Close()
+	req = httptest.NewRequest("POST", "/large", bytes.NewReader(buf.Bytes()))
+	req.Header.Set("Content-Encoding", "gzip")
+	assert.True(t, buf.Len() < 1000)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(req).Code)
+
+	for content, code := range map[string]int{
+		`{"Data": [[[1]]]}`:                                  http.StatusNoContent,
+		`{"Data": [[[[1]]]]}`:                                http.StatusBadRequest,
+		`{"Data": [{"a": {"b": 1}}]}`:                        http.StatusNoContent,
+		`{"Data": [{"a": {"b": {}}}]}`:                       http.StatusBadRequest,
+		`{"Data": [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]}`:          http.StatusNoContent,
+		`{"Data": [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11]}`:      http.StatusBadRequest,
+		`{"Data": [[1, 2, 3, 4, 5, 6], [1, 2, 3, 4, 5, 6]]}`: http.StatusNoContent,
+	} {
+		w := post(body("/large", content))
+		assert.Equal(t, code, w.Code, content)
+		if code == http.StatusBadRequest {
+			content, _ := ioutil.ReadAll(w.Body)
+			assert.Contains(t, string(content), hermes.CodeInvalidArgument)
+		}
+	}
+}
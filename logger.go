@@ -0,0 +1,116 @@
+package hermes
+
+import (
+	"context"
+	"time"
+
+	"github.com/apourchet/hermes/binding"
+	"github.com/golang/glog"
+)
+
+// Structured logger, which *slog.Logger implements. The arguments are
+// alternating keys and values
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+var DefaultLogger Logger = GlogLogger{}
+
+// The logger of the request is in its context under that key, so that the
+// bindings log with it too
+const loggerKey = binding.LoggerKey
+
+const startKey = "Hermes-Start"
+
+// Returns the logger of the request, with its request id, handler, method,
+// path and remote address as fields. Returns DefaultLogger outside of
+// requests
+func LoggerFrom(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerKey).(Logger); ok && logger != nil {
+		return logger
+	}
+	return DefaultLogger
+}
+
+// Returns a logger that adds the fields to every entry
+func WithFields(logger Logger, args ...interface{}) Logger {
+	if fl, ok := logger.(*fieldLogger); ok {
+		return &fieldLogger{fl.logger, append(append([]interface{}{}, fl.fields...), args...)}
+	}
+	return &fieldLogger{logger, args}
+}
+
+type fieldLogger struct {
+	logger Logger
+	fields []interface{}
+}
+
+func (fl *fieldLogger) with(args []interface{}) []interface{} {
+	return append(append([]interface{}{}, fl.fields...), args...)
+}
+
+func (fl *fieldLogger) Debug(msg string, args ...interface{}) { fl.logger.Debug(msg, fl.with(args)...) }
+func (fl *fieldLogger) Info(msg string, args ...interface{})  { fl.logger.Info(msg, fl.with(args)...) }
+func (fl *fieldLogger) Warn(msg string, args ...interface{})  { fl.logger.Warn(msg, fl.with(args)...) }
+func (fl *fieldLogger) Error(msg string, args ...interface{}) { fl.logger.Error(msg, fl.with(args)...) }
+
+// Writes entries to glog as the message followed by key=value pairs. Debug
+// entries need -v=1
+type GlogLogger struct{}
+
+func (_ GlogLogger) Debug(msg string, args ...interface{}) {
+	glog.V(1).Info(binding.FormatLogEntry(msg, args))
+}
+
+func (_ GlogLogger) Info(msg string, args ...interface{}) {
+	glog.Info(binding.FormatLogEntry(msg, args))
+}
+
+func (_ GlogLogger) Warn(msg string, args ...interface{}) {
+	glog.Warning(binding.FormatLogEntry(msg, args))
+}
+
+func (_ GlogLogger) Error(msg string, args ...interface{}) {
+	glog.Error(binding.FormatLogEntry(msg, args))
+}
+
+func (router *Router) logger() Logger {
+	if router.Logger == nil {
+		return DefaultLogger
+	}
+	return router.Logger
+}
+
+func (caller *Caller) logCall(ctx context.Context, methodname string, start time.Time, code int, err error) {
+	args := []interface{}{
+		"request_id", GetRequestID(ctx),
+		"sni", caller.callable.SNI(),
+		"handler", methodname,
+		"status", code,
+		"latency", time.Since(start),
+	}
+	if err != nil {
+		caller.Logger.Warn("Call failed", append(args, "error", err)...)
+		return
+	}
+	caller.Logger.Debug("Call succeeded", args...)
+}
+
+// The logger of requests outside of routers, which only have their path
+func requestLogger(ctx context.Context, path string) Logger {
+	if logger, ok := ctx.Value(loggerKey).(Logger); ok && logger != nil {
+		return logger
+	}
+	return WithFields(DefaultLogger, "request_id", GetRequestID(ctx), "path", path)
+}
+
+// Time since the router started serving the request
+func elapsed(ctx context.Context) time.Duration {
+	if start, ok := ctx.Value(startKey).(time.Time); ok {
+		return time.Since(start)
+	}
+	return 0
+}
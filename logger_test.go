@@ -0,0 +1,147 @@
+package hermes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type LoggedService struct{}
+
+type LoggedInput struct {
+	Name  string `hermes:"query=name"`
+	Count int    `hermes:"query=count"`
+}
+
+func (_ LoggedService) SNI() string { return "logged" }
+
+func (_ LoggedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Greet", "GET", "/greet", LoggedInput{}, nil),
+	}
+}
+
+func (_ LoggedService) Greet(ctx context.Context, in *LoggedInput) error {
+	hermes.LoggerFrom(ctx).Info("Greeting", "name", in.Name)
+	if in.Name == "" {
+		return hermes.InvalidArgument("Missing name")
+	}
+	return nil
+}
+
+func readEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	entries := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRouterLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	engine := gin.New()
+	router := hermes.NewRouter(LoggedService{})
+	router.Logger = slog.New(slog.NewJSONHandler(buf, nil))
+	require.NoError(t, router.Serve(engine))
+
+	req := httptest.NewRequest("GET", "/greet?name=alice", nil)
+	req.Header.Set("Hermes-Request-ID", "rid-1")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	entries := readEntries(t, buf)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Equal(t, "rid-1", entry["request_id"])
+		assert.Equal(t, "Greet", entry["handler"])
+		assert.Equal(t, "GET", entry["method"])
+		assert.Equal(t, "/greet", entry["path"])
+		assert.Equal(t, req.RemoteAddr, entry["remote_addr"])
+	}
+	assert.Equal(t, "Greeting", entries[0]["msg"])
+	assert.Equal(t, "alice", entries[0]["name"])
+	assert.Equal(t, "Request served", entries[1]["msg"])
+	assert.Equal(t, float64(http.StatusNoContent), entries[1]["status"])
+	assert.Contains(t, entries[1], "latency")
+
+	buf.Reset()
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/greet", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	entries = readEntries(t, buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "WARN", entries[1]["level"])
+	assert.Equal(t, "Request failed", entries[1]["msg"])
+	assert.Equal(t, float64(http.StatusBadRequest), entries[1]["status"])
+	assert.Contains(t, entries[1]["error"], "Missing name")
+
+	// Requests that fail before reaching the handler are logged too
+	buf.Reset()
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/greet?count=many", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	entries = readEntries(t, buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Request failed", entries[0]["msg"])
+	assert.Equal(t, float64(http.StatusBadRequest), entries[0]["status"])
+	assert.Equal(t, "Greet", entries[0]["handler"])
+}
+
+func TestCallerLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(LoggedService{}).Serve(engine))
+	caller := hermes.NewCaller(LoggedService{})
+	caller.Client = &hermes.MockClient{engine}
+	caller.Logger = slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	ctx := hermes.SetRequestID(context.Background(), "rid-2")
+	_, err := caller.Call(ctx, "Greet", &LoggedInput{Name: "bob"}, nil)
+	require.NoError(t, err)
+	_, err = caller.Call(ctx, "Greet", &LoggedInput{}, nil)
+	require.Error(t, err)
+
+	entries := readEntries(t, buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "DEBUG", entries[0]["level"])
+	assert.Equal(t, "Call succeeded", entries[0]["msg"])
+	assert.Equal(t, "WARN", entries[1]["level"])
+	assert.Equal(t, "Call failed", entries[1]["msg"])
+	assert.Contains(t, entries[1]["error"], "Missing name")
+	for _, entry := range entries {
+		assert.Equal(t, "rid-2", entry["request_id"])
+		assert.Equal(t, "logged", entry["sni"])
+		assert.Equal(t, "Greet", entry["handler"])
+		assert.Contains(t, entry, "status")
+		assert.Contains(t, entry, "latency")
+	}
+}
+
+func TestLoggerOutsideRequests(t *testing.T) {
+	defaultLogger := hermes.DefaultLogger
+	defer func() { hermes.DefaultLogger = defaultLogger }()
+	buf := &bytes.Buffer{}
+	hermes.DefaultLogger = slog.New(slog.NewJSONHandler(buf, nil))
+
+	assert.Equal(t, hermes.DefaultLogger, hermes.LoggerFrom(context.Background()))
+	logger := hermes.WithFields(hermes.WithFields(hermes.DefaultLogger, "a", 1), "b", 2)
+	logger.Info("Entry", "c", 3)
+	entries := readEntries(t, buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, float64(1), entries[0]["a"])
+	assert.Equal(t, float64(2), entries[0]["b"])
+	assert.Equal(t, float64(3), entries[0]["c"])
+}
@@ -0,0 +1,268 @@
+package hermes
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const metricsKey = "Hermes-Metrics"
+
+// The upper bounds of the latency histograms, in seconds
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registries stop adding series past that many, so that unexpected label
+// values cannot make them grow forever
+var DefaultMaxMetricSeries = 10000
+
+const (
+	counterMetric   = "counter"
+	gaugeMetric     = "gauge"
+	histogramMetric = "histogram"
+)
+
+var metricFamilies = map[string]struct{ kind, help string }{
+	"hermes_requests_total":                  {counterMetric, "Requests served, by endpoint and status code."},
+	"hermes_request_duration_seconds":        {histogramMetric, "Latency of the requests served, by endpoint."},
+	"hermes_requests_in_flight":              {gaugeMetric, "Requests being served, by endpoint."},
+	"hermes_binding_failures_total":          {counterMetric, "Requests whose input could not be bound, by endpoint."},
+	"hermes_client_requests_total":           {counterMetric, "Calls made, by callable, endpoint and status code."},
+	"hermes_client_request_duration_seconds": {histogramMetric, "Latency of the calls made, by callable and endpoint."},
+	"hermes_client_requests_in_flight":       {gaugeMetric, "Calls in progress, by callable and endpoint."},
+	"hermes_client_binding_failures_total":   {counterMetric, "Calls whose input could not be applied, by callable and endpoint."},
+	"hermes_metric_series_dropped_total":     {counterMetric, "Samples dropped because the registry reached its maximum number of series."},
+}
+
+// Holds the metrics of routers and callers, and writes them in the
+// Prometheus text exposition format
+type MetricRegistry struct {
+	MaxSeries int
+
+	buckets []float64
+	lock    sync.Mutex
+	series  map[string]map[string]*metricSeries
+	count   int
+	dropped uint64
+}
+
+type metricSeries struct {
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// The histograms of the registry have the given buckets, or the
+// DefaultLatencyBuckets if there are none. They cannot change afterwards
+func NewMetricRegistry(buckets ...float64) *MetricRegistry {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	return &MetricRegistry{
+		MaxSeries: DefaultMaxMetricSeries,
+		buckets:   append([]float64(nil), buckets...),
+		series:    map[string]map[string]*metricSeries{},
+	}
+}
+
+func (registry *MetricRegistry) Buckets() []float64 {
+	return append([]float64(nil), registry.buckets...)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Formats pairs of label names and values
+func metricLabels(pairs ...string) string {
+	labels := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(pairs[i+1])))
+	}
+	return strings.Join(labels, ",")
+}
+
+// Returns nil once the registry has MaxSeries series
+func (registry *MetricRegistry) get(name, labels string) *metricSeries {
+	if series, found := registry.series[name][labels]; found {
+		return series
+	}
+	if registry.MaxSeries > 0 && registry.count >= registry.MaxSeries {
+		registry.dropped++
+		return nil
+	}
+
+	family, found := registry.series[name]
+	if !found {
+		family = map[string]*metricSeries{}
+		registry.series[name] = family
+	}
+	series := &metricSeries{counts: make([]uint64, len(registry.buckets))}
+	family[labels] = series
+	registry.count++
+	return series
+}
+
+// Adds to a counter or a gauge
+func (registry *MetricRegistry) Add(name, labels string, delta float64) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if series := registry.get(name, labels); series != nil {
+		series.value += delta
+	}
+}
+
+// Records a value in a histogram
+func (registry *MetricRegistry) Observe(name, labels string, value float64) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	series := registry.get(name, labels)
+	if series == nil {
+		return
+	}
+	for i, bound := range registry.buckets {
+		if value <= bound {
+			series.counts[i]++
+		}
+	}
+	series.sum += value
+	series.count++
+}
+
+// Returns the value of a counter or a gauge, 0 if it was never set
+func (registry *MetricRegistry) Value(name, labels string) float64 {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if series, found := registry.series[name][labels]; found {
+		return series.value
+	}
+	return 0
+}
+
+func (registry *MetricRegistry) WriteTo(w io.Writer) (int64, error) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	buf := &strings.Builder{}
+	names := []string{}
+	for name := range registry.series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		family := metricFamilies[name]
+		if family.kind == "" {
+			family.kind = "untyped"
+		}
+		if family.help != "" {
+			fmt.Fprintf(buf, "# HELP %s %s\n", name, family.help)
+		}
+		fmt.Fprintf(buf, "# TYPE %s %s\n", name, family.kind)
+
+		labelsets := []string{}
+		for labels := range registry.series[name] {
+			labelsets = append(labelsets, labels)
+		}
+		sort.Strings(labelsets)
+		for _, labels := range labelsets {
+			series := registry.series[name][labels]
+			if family.kind != histogramMetric {
+				fmt.Fprintf(buf, "%s%s %s\n", name, braces(labels), formatFloat(series.value))
+				continue
+			}
+			for i, bound := range registry.buckets {
+				fmt.Fprintf(buf, "%s_bucket%s %d\n", name, braces(joinLabels(labels, metricLabels("le", formatFloat(bound)))), series.counts[i])
+			}
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", name, braces(joinLabels(labels, `le="+Inf"`)), series.count)
+			fmt.Fprintf(buf, "%s_sum%s %s\n", name, braces(labels), formatFloat(series.sum))
+			fmt.Fprintf(buf, "%s_count%s %d\n", name, braces(labels), series.count)
+		}
+	}
+
+	if registry.dropped != 0 {
+		name := "hermes_metric_series_dropped_total"
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, metricFamilies[name].help, name, counterMetric)
+		fmt.Fprintf(buf, "%s %d\n", name, registry.dropped)
+	}
+
+	n, err := io.WriteString(w, buf.String())
+	return int64(n), err
+}
+
+// The SNI of the server if it has one, its type name otherwise
+func serverSNI(server Server) string {
+	if callable, ok := server.(ICallable); ok {
+		return callable.SNI()
+	}
+	return serverName(server)
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func joinLabels(labels, more string) string {
+	if labels == "" {
+		return more
+	}
+	return labels + "," + more
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Records the metrics of a request served by the endpoint, and returns the
+// function to call once it is done
+func (registry *MetricRegistry) serving(sni string, ep *Endpoint) func(code int) {
+	labels := metricLabels("sni", sni, "handler", ep.Handler)
+	registry.Add("hermes_requests_in_flight", labels, 1)
+	start := time.Now()
+	return func(code int) {
+		registry.Add("hermes_requests_in_flight", labels, -1)
+		registry.Observe("hermes_request_duration_seconds", labels, time.Since(start).Seconds())
+		registry.Add("hermes_requests_total", metricLabels("sni", sni, "handler", ep.Handler, "code", strconv.Itoa(code)), 1)
+	}
+}
+
+// Same as serving, for the calls of callers. Calls that failed before
+// getting a response have the code "error"
+func (registry *MetricRegistry) calling(sni, handler string) func(resp *http.Response) {
+	labels := metricLabels("sni", sni, "handler", handler)
+	registry.Add("hermes_client_requests_in_flight", labels, 1)
+	start := time.Now()
+	return func(resp *http.Response) {
+		code := "error"
+		if resp != nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		registry.Add("hermes_client_requests_in_flight", labels, -1)
+		registry.Observe("hermes_client_request_duration_seconds", labels, time.Since(start).Seconds())
+		registry.Add("hermes_client_requests_total", metricLabels("sni", sni, "handler", handler, "code", code), 1)
+	}
+}
+
+// Serves the metrics of the Router once hermes.Metrics is added to the
+// EndpointMap of the service it is embedded in
+type MetricsExporter struct{}
+
+func (_ MetricsExporter) Metrics(ctx *gin.Context) (int, error) {
+	registry, ok := ctx.Value(metricsKey).(*MetricRegistry)
+	if !ok || registry == nil {
+		return http.StatusNotFound, NotFound("No metrics are recorded")
+	}
+	ctx.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	ctx.Status(http.StatusOK)
+	registry.WriteTo(ctx.Writer)
+	return HERMES_CODE_BYPASS, nil
+}
+
+var Metrics = NewEndpoint("Metrics", "GET", "/hermes/metrics", nil, nil).Public()
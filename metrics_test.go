@@ -0,0 +1,131 @@
+package hermes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MeteredService struct {
+	hermes.MetricsExporter
+}
+
+type MeteredInput struct {
+	Name string `hermes:"query=name" validate:"required"`
+}
+
+func (_ MeteredService) SNI() string { return "metered" }
+
+func (_ MeteredService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.Metrics,
+		hermes.EP("Greet", "GET", "/greet", MeteredInput{}, nil),
+	}
+}
+
+func (_ MeteredService) Greet(ctx context.Context, in *MeteredInput) error {
+	if in.Name == "nobody" {
+		return hermes.NotFound("No such person")
+	}
+	return nil
+}
+
+func TestMetrics(t *testing.T) {
+	registry := hermes.NewMetricRegistry()
+	engine := gin.New()
+	router := hermes.NewRouter(MeteredService{})
+	router.Metrics = registry
+	require.NoError(t, router.Serve(engine))
+
+	caller := hermes.NewCaller(MeteredService{})
+	caller.Client = &hermes.MockClient{engine}
+	caller.Metrics = registry
+
+	code, err := caller.Call(context.Background(), "Greet", &MeteredInput{"alice"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = caller.Call(context.Background(), "Greet", &MeteredInput{"nobody"}, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/greet", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/hermes/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+	out := w.Body.String()
+
+	for _, line := range []string{
+		`# TYPE hermes_requests_total counter`,
+		`hermes_requests_total{sni="metered",handler="Greet",code="204"} 1`,
+		`hermes_requests_total{sni="metered",handler="Greet",code="404"} 1`,
+		`hermes_requests_total{sni="metered",handler="Greet",code="400"} 1`,
+		`hermes_binding_failures_total{sni="metered",handler="Greet"} 1`,
+		`hermes_requests_in_flight{sni="metered",handler="Greet"} 0`,
+		`# TYPE hermes_request_duration_seconds histogram`,
+		`hermes_request_duration_seconds_bucket{sni="metered",handler="Greet",le="+Inf"} 3`,
+		`hermes_request_duration_seconds_count{sni="metered",handler="Greet"} 3`,
+		`hermes_client_requests_total{sni="metered",handler="Greet",code="204"} 1`,
+		`hermes_client_requests_total{sni="metered",handler="Greet",code="404"} 1`,
+		`hermes_client_request_duration_seconds_count{sni="metered",handler="Greet"} 2`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+
+	// The metrics endpoint is being served while it writes them
+	assert.Contains(t, out, `hermes_requests_in_flight{sni="metered",handler="Metrics"} 1`)
+}
+
+func TestClientBindingFailures(t *testing.T) {
+	registry := hermes.NewMetricRegistry()
+	caller := hermes.NewCaller(MeteredService{})
+	caller.Client = &hermes.MockClient{gin.New()}
+	caller.Metrics = registry
+
+	code, err := caller.Call(context.Background(), "Greet", &MeteredInput{}, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, 1.0, registry.Value("hermes_client_binding_failures_total", `sni="metered",handler="Greet"`))
+
+	// No request was sent
+	assert.Equal(t, 1.0, registry.Value("hermes_client_requests_total", `sni="metered",handler="Greet",code="error"`))
+	assert.Equal(t, 0.0, registry.Value("hermes_client_requests_total", `sni="metered",handler="Greet",code="400"`))
+}
+
+func TestMetricRegistryBounds(t *testing.T) {
+	registry := hermes.NewMetricRegistry(0.1, 1)
+	registry.Observe("hermes_request_duration_seconds", `a="1"`, 0.5)
+
+	// The buckets of existing series cannot change
+	buckets := registry.Buckets()
+	buckets[0] = 10
+	registry.Observe("hermes_request_duration_seconds", `a="2"`, 0.5)
+	assert.Equal(t, []float64{0.1, 1}, registry.Buckets())
+
+	registry.MaxSeries = 2
+	registry.Add("hermes_requests_total", `a="3"`, 1)
+	registry.Observe("hermes_request_duration_seconds", `a="1"`, 2)
+	assert.Equal(t, 0.0, registry.Value("hermes_requests_total", `a="3"`))
+
+	out := &strings.Builder{}
+	_, err := registry.WriteTo(out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), `hermes_request_duration_seconds_count{a="1"} 2`+"\n")
+	assert.Contains(t, out.String(), `hermes_request_duration_seconds_bucket{a="2",le="0.1"} 0`+"\n")
+	assert.Contains(t, out.String(), "hermes_metric_series_dropped_total 1\n")
+}
+
+func TestRoutersHaveTheirOwnMetrics(t *testing.T) {
+	first, second := hermes.NewRouter(MeteredService{}), hermes.NewRouter(MeteredService{})
+	require.NotNil(t, first.Metrics)
+	assert.NotSame(t, first.Metrics, second.Metrics)
+	assert.Nil(t, hermes.NewCaller(MeteredService{}).Metrics)
+}
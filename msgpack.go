@@ -0,0 +1,322 @@
+package hermes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Encodes values as MessagePack. Values go through their JSON
+// representation first, so json struct tags and Marshalers apply the same
+// way they do for the JSON encoder
+type MsgPackEncoder struct{}
+
+func (_ MsgPackEncoder) MediaType() string { return "application/msgpack" }
+
+func (_ MsgPackEncoder) Encode(w io.Writer, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(w)
+	if err := writeMsgPack(buf, generic); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+func (_ MsgPackEncoder) Decode(r io.Reader, v interface{}) error {
+	generic, err := readMsgPack(bufio.NewReader(r), 0)
+	if err != nil {
+		return fmt.Errorf("Failed to decode msgpack: %v", err)
+	}
+	content, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+func writeMsgPack(w *bufio.Writer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return w.WriteByte(0xc0)
+	case bool:
+		if v {
+			return w.WriteByte(0xc3)
+		}
+		return w.WriteByte(0xc2)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return writeMsgPackInt(w, i)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		w.WriteByte(0xcb)
+		return binary.Write(w, binary.BigEndian, f)
+	case string:
+		writeMsgPackHeader(w, len(v), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		_, err := w.WriteString(v)
+		return err
+	case []interface{}:
+		writeMsgPackHeader(w, len(v), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := writeMsgPack(w, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		writeMsgPackHeader(w, len(v), 0x80, 15, 0, 0xde, 0xdf)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := writeMsgPack(w, key); err != nil {
+				return err
+			}
+			if err := writeMsgPack(w, v[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("Cannot encode %T as msgpack", v)
+}
+
+// Writes the header of a string, array or map given the prefix of its
+// fixed size format and the markers of its 8, 16 and 32 bit formats
+func writeMsgPackHeader(w *bufio.Writer, length int, fixed byte, maxfixed int, m8, m16, m32 byte) {
+	switch {
+	case length <= maxfixed:
+		w.WriteByte(fixed | byte(length))
+	case m8 != 0 && length <= math.MaxUint8:
+		w.WriteByte(m8)
+		w.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		w.WriteByte(m16)
+		binary.Write(w, binary.BigEndian, uint16(length))
+	default:
+		w.WriteByte(m32)
+		binary.Write(w, binary.BigEndian, uint32(length))
+	}
+}
+
+func writeMsgPackInt(w *bufio.Writer, i int64) error {
+	switch {
+	case i >= 0 && i <= 127:
+		return w.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		return w.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		w.WriteByte(0xd0)
+		return binary.Write(w, binary.BigEndian, int8(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		w.WriteByte(0xd1)
+		return binary.Write(w, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		w.WriteByte(0xd2)
+		return binary.Write(w, binary.BigEndian, int32(i))
+	}
+	w.WriteByte(0xd3)
+	return binary.Write(w, binary.BigEndian, i)
+}
+
+func readMsgPack(r *bufio.Reader, depth int) (interface{}, error) {
+	if depth > maxMsgPackDepth {
+		return nil, fmt.Errorf("Msgpack content nested deeper than %d levels", maxMsgPackDepth)
+	}
+
+	marker, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case marker <= 0x7f:
+		return int64(marker), nil
+	case marker >= 0xe0:
+		return int64(int8(marker)), nil
+	case marker&0xf0 == 0x80:
+		return readMsgPackMap(r, int(marker&0x0f), depth)
+	case marker&0xf0 == 0x90:
+		return readMsgPackArray(r, int(marker&0x0f), depth)
+	case marker&0xe0 == 0xa0:
+		return readMsgPackString(r, int(marker&0x1f))
+	}
+
+	switch marker {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		n, err := readMsgPackLength(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgPackString(r, n)
+	case 0xc5, 0xda:
+		n, err := readMsgPackLength(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgPackString(r, n)
+	case 0xc6, 0xdb:
+		n, err := readMsgPackLength(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgPackString(r, n)
+	case 0xca:
+		var f float32
+		err := binary.Read(r, binary.BigEndian, &f)
+		return float64(f), err
+	case 0xcb:
+		var f float64
+		err := binary.Read(r, binary.BigEndian, &f)
+		return f, err
+	case 0xcc:
+		var i uint8
+		err := binary.Read(r, binary.BigEndian, &i)
+		return uint64(i), err
+	case 0xcd:
+		var i uint16
+		err := binary.Read(r, binary.BigEndian, &i)
+		return uint64(i), err
+	case 0xce:
+		var i uint32
+		err := binary.Read(r, binary.BigEndian, &i)
+		return uint64(i), err
+	case 0xcf:
+		var i uint64
+		err := binary.Read(r, binary.BigEndian, &i)
+		return i, err
+	case 0xd0:
+		var i int8
+		err := binary.Read(r, binary.BigEndian, &i)
+		return int64(i), err
+	case 0xd1:
+		var i int16
+		err := binary.Read(r, binary.BigEndian, &i)
+		return int64(i), err
+	case 0xd2:
+		var i int32
+		err := binary.Read(r, binary.BigEndian, &i)
+		return int64(i), err
+	case 0xd3:
+		var i int64
+		err := binary.Read(r, binary.BigEndian, &i)
+		return i, err
+	case 0xdc:
+		n, err := readMsgPackLength(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgPackArray(r, n, depth)
+	case 0xdd:
+		n, err := readMsgPackLength(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgPackArray(r, n, depth)
+	case 0xde:
+		n, err := readMsgPackLength(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgPackMap(r, n, depth)
+	case 0xdf:
+		n, err := readMsgPackLength(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgPackMap(r, n, depth)
+	}
+	return nil, fmt.Errorf("Unsupported msgpack marker 0x%x", marker)
+}
+
+const (
+	maxMsgPackInitLen = 4096
+	maxMsgPackDepth   = 1000
+)
+
+func msgPackInitLen(n int) int {
+	if n > maxMsgPackInitLen {
+		return maxMsgPackInitLen
+	}
+	return n
+}
+
+func readMsgPackLength(r *bufio.Reader, size int) (int, error) {
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, b := range buf {
+		n = n<<8 | int(b)
+	}
+	return n, nil
+}
+
+// The lengths come from the content, so buffers grow as the content
+// arrives instead of being allocated upfront. Truncated or hostile content
+// then fails on EOF before it can make the decoder allocate gigabytes
+func readMsgPackString(r *bufio.Reader, n int) (interface{}, error) {
+	buf := &bytes.Buffer{}
+	if _, err := io.CopyN(buf, r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.String(), nil
+}
+
+func readMsgPackArray(r *bufio.Reader, n int, depth int) (interface{}, error) {
+	items := make([]interface{}, 0, msgPackInitLen(n))
+	for i := 0; i < n; i++ {
+		item, err := readMsgPack(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func readMsgPackMap(r *bufio.Reader, n int, depth int) (interface{}, error) {
+	m := make(map[string]interface{}, msgPackInitLen(n))
+	for i := 0; i < n; i++ {
+		key, err := readMsgPack(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readMsgPack(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprintf("%v", key)] = value
+	}
+	return m, nil
+}
@@ -0,0 +1,232 @@
+package hermes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apourchet/hermes/binding"
+)
+
+const openAPIVersion = "3.0.3"
+
+// Where each hermes struct tag directive puts its field in a request
+var openAPILocations = map[string]string{
+	"path":   "path",
+	"query":  "query",
+	"header": "header",
+	"cookie": "cookie",
+}
+
+// Generates an OpenAPI 3 document describing the endpoints of the server.
+// Path parameters, queries, headers and hermes struct tags of input types
+// become parameters, the remaining fields of the input the JSON body
+func OpenAPI(server Server) ([]byte, error) {
+	schemas := binding.NewSchemaGenerator("#/components/schemas/")
+	schemas.Nullable = true
+	paths := map[string]map[string]interface{}{}
+
+	for _, ep := range server.Endpoints() {
+		path := openAPIPath(fullPath(server, ep))
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		method := strings.ToLower(ep.Method)
+		if _, found := paths[path][method]; found {
+			return nil, fmt.Errorf("Endpoint '%s' is mapped to an operation that already exists: %s %s", ep.Handler, ep.Method, path)
+		}
+		paths[path][method] = openAPIOperation(schemas, server, ep)
+	}
+
+	doc := map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   serverName(server),
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.Definitions,
+		},
+	}
+	if callable, ok := server.(ICallable); ok {
+		doc["servers"] = []interface{}{map[string]interface{}{"url": "http://" + callable.SNI()}}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func serverName(server Server) string {
+	t := reflect.TypeOf(server)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// Turns "/users/:id/*rest" into "/users/{id}/{rest}"
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func openAPIOperation(schemas *binding.SchemaGenerator, server Server, ep *Endpoint) map[string]interface{} {
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schemas.Schema(reflect.TypeOf(Error{}))},
+		},
+	}
+	success := map[string]interface{}{"description": "Success"}
+	if ep.OutputType != nil {
+		contentType := JSONEncoder{}.MediaType()
+		if ep.Streaming != "" {
+			contentType = string(ep.Streaming)
+		}
+		success["content"] = map[string]interface{}{
+			contentType: map[string]interface{}{"schema": schemas.Schema(ep.OutputType)},
+		}
+	}
+
+	operation := map[string]interface{}{
+		"operationId": ep.Handler,
+		"responses": map[string]interface{}{
+			"200":     success,
+			"400":     errorResponse,
+			"default": errorResponse,
+		},
+	}
+
+	parameters, body := openAPIInput(schemas, server, ep)
+	if len(parameters) != 0 {
+		operation["parameters"] = parameters
+	}
+	if body != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": body},
+			},
+		}
+	}
+	return operation
+}
+
+// Splits the input type of the endpoint into parameters and a body schema
+func openAPIInput(schemas *binding.SchemaGenerator, server Server, ep *Endpoint) ([]interface{}, interface{}) {
+	parameters, body := openAPIBoundInput(schemas, ep)
+
+	// Every parameter of the path must be declared, bound to a field or not
+	declared := map[string]bool{}
+	for _, param := range parameters {
+		if param := param.(map[string]interface{}); param["in"] == "path" {
+			declared[param["name"].(string)] = true
+		}
+	}
+	for param := range pathParams(fullPath(server, ep)) {
+		if !declared[param] {
+			parameters = append(parameters, openAPIParameter(param, "path", map[string]interface{}{"type": "string"}))
+		}
+	}
+
+	sort.SliceStable(parameters, func(i, j int) bool {
+		a, b := parameters[i].(map[string]interface{}), parameters[j].(map[string]interface{})
+		if a["in"] != b["in"] {
+			return a["in"].(string) < b["in"].(string)
+		}
+		return a["name"].(string) < b["name"].(string)
+	})
+	return parameters, body
+}
+
+func openAPIBoundInput(schemas *binding.SchemaGenerator, ep *Endpoint) ([]interface{}, interface{}) {
+	parameters := []interface{}{}
+	if ep.InputType == nil {
+		return parameters, nil
+	}
+
+	input := ep.InputType
+	for input.Kind() == reflect.Ptr {
+		input = input.Elem()
+	}
+	if input.Kind() != reflect.Struct {
+		return parameters, schemas.Schema(ep.InputType)
+	}
+
+	// Fields bound elsewhere than the body, keyed by lowercase field name
+	bound := map[string]bool{}
+	fields := map[string]reflect.StructField{}
+	for _, field := range binding.JSONFields(input) {
+		fields[strings.ToLower(field.Name)] = field
+	}
+	addParameter := func(name, location, fieldname string) {
+		field, found := fields[strings.ToLower(fieldname)]
+		if !found {
+			return
+		}
+		bound[strings.ToLower(fieldname)] = true
+		parameters = append(parameters, openAPIParameter(name, location, schemas.Schema(field.Type)))
+	}
+
+	for _, param := range ep.Params {
+		addParameter(param, "path", param)
+	}
+	for _, query := range ep.Queries {
+		addParameter(query, "query", query)
+	}
+	for header, fieldname := range ep.Headers {
+		addParameter(header, "header", fieldname)
+	}
+	for fieldname, directives := range binding.Directives(input) {
+		for _, directive := range directives {
+			tagkey, tagval, err := binding.ParseDirective(directive)
+			if location, known := openAPILocations[tagkey]; err == nil && known {
+				addParameter(tagval, location, fieldname)
+			}
+		}
+	}
+
+	body := schemas.StructSchema(input)
+	properties := body["properties"].(map[string]interface{})
+	required := []interface{}{}
+	for _, field := range binding.JSONFields(input) {
+		name, _ := binding.JSONName(field)
+		if bound[strings.ToLower(field.Name)] {
+			delete(properties, name)
+		} else if _, found := properties[name]; found && contains(body["required"], name) {
+			required = append(required, name)
+		}
+	}
+
+	if len(properties) == 0 {
+		return parameters, nil
+	}
+	delete(body, "required")
+	if len(required) != 0 {
+		body["required"] = required
+	}
+	return parameters, body
+}
+
+func contains(list interface{}, value interface{}) bool {
+	items, _ := list.([]interface{})
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func openAPIParameter(name, location string, schema interface{}) map[string]interface{} {
+	param := map[string]interface{}{"name": name, "in": location, "schema": schema}
+	if location == "path" {
+		param["required"] = true
+	}
+	return param
+}
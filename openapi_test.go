@@ -0,0 +1,80 @@
+package hermes_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+	content, err := hermes.OpenAPI(IntrospectedService{})
+	require.NoError(t, err)
+
+	doc := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(content, &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Equal(t, "IntrospectedService", doc["info"].(map[string]interface{})["title"])
+
+	paths := doc["paths"].(map[string]interface{})
+	assert.Contains(t, paths, "/hermes/healthz")
+	assert.Contains(t, paths, "/hermes/endpoints")
+
+	find := paths["/trees/{action}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, "Find", find["operationId"])
+
+	expected := `[
+		{"name": "Authorization", "in": "header", "schema": {"type": "string"}},
+		{"name": "X-Trace", "in": "header", "schema": {"type": "string"}},
+		{"name": "action", "in": "path", "required": true, "schema": {"type": "string"}},
+		{"name": "q", "in": "query", "schema": {"type": "string"}}
+	]`
+	parameters, _ := json.Marshal(find["parameters"])
+	assert.JSONEq(t, expected, string(parameters))
+
+	// Only the fields that are not bound elsewhere make up the body
+	body, _ := json.Marshal(find["requestBody"])
+	assert.JSONEq(t, `{"content": {"application/json": {"schema": {
+		"type": "object",
+		"properties": {"Limit": {"type": "integer", "format": "int64", "nullable": true}}
+	}}}}`, string(body))
+
+	responses, _ := json.Marshal(find["responses"].(map[string]interface{})["200"])
+	assert.JSONEq(t, `{"description": "Success", "content": {"application/json": {"schema": {
+		"type": "object", "nullable": true,
+		"additionalProperties": {"$ref": "#/components/schemas/hermes_test.Tree"}
+	}}}}`, string(responses))
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	tree, _ := json.Marshal(schemas["hermes_test.Tree"])
+	assert.JSONEq(t, `{"type": "object", "required": ["value"], "properties": {
+		"value": {"type": "integer", "format": "int64"},
+		"children": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/hermes_test.Tree"}}
+	}}`, string(tree))
+	assert.Contains(t, schemas, "hermes.Error")
+}
+
+func TestOpenAPIPathParameters(t *testing.T) {
+	content, err := hermes.OpenAPI(&MyService{})
+	require.NoError(t, err)
+
+	doc := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(content, &doc))
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "http://localhost:9000"}}, doc["servers"])
+
+	paths := doc["paths"].(map[string]interface{})
+	tagged := paths["/tagged/{p1}"].(map[string]interface{})["get"].(map[string]interface{})
+	parameters, _ := json.Marshal(tagged["parameters"])
+	assert.JSONEq(t, `[
+		{"name": "h1", "in": "header", "schema": {"type": "string", "nullable": true}},
+		{"name": "p1", "in": "path", "required": true, "schema": {"type": "string"}},
+		{"name": "q1", "in": "query", "schema": {"type": "integer", "format": "int64"}}
+	]`, string(parameters))
+	assert.NotContains(t, tagged, "requestBody")
+
+	raw := paths["/rawtype"].(map[string]interface{})["get"].(map[string]interface{})
+	body, _ := json.Marshal(raw["requestBody"])
+	assert.JSONEq(t, `{"content": {"application/json": {"schema": {"type": "integer", "format": "int64"}}}}`, string(body))
+}
@@ -0,0 +1,190 @@
+package hermes
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Extracts the key that requests are rate limited by
+type RateKey func(inv *Invocation) string
+
+// Limits by the address of the peer. Headers like X-Forwarded-For are
+// ignored since any client can set them, see ByForwardedClientIP
+func ByClientIP(inv *Invocation) string {
+	return remoteIP(inv.Gin.Request)
+}
+
+// Limits by the client address that the trusted proxies, given as IPs or
+// CIDRs, report in X-Forwarded-For. That is the rightmost address of the
+// header that is not a trusted proxy. Requests that do not come from a
+// trusted proxy are limited by their peer address
+func ByForwardedClientIP(trusted ...string) RateKey {
+	networks := []*net.IPNet{}
+	for _, proxy := range trusted {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid trusted proxy %s: %v", proxy, err))
+		}
+		networks = append(networks, network)
+	}
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		for _, network := range networks {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(inv *Invocation) string {
+		client := remoteIP(inv.Gin.Request)
+		if !isTrusted(client) {
+			return client
+		}
+		hops := strings.Split(strings.Join(inv.Gin.Request.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			client = hop
+			if !isTrusted(hop) {
+				break
+			}
+		}
+		return client
+	}
+}
+
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func ByHeader(header string) RateKey {
+	return func(inv *Invocation) string {
+		return inv.Gin.Request.Header.Get(header)
+	}
+}
+
+// Limits by the value of a field of the bound input
+func ByField(fieldname string) RateKey {
+	return func(inv *Invocation) string {
+		if inv.Input == nil {
+			return ""
+		}
+		input := reflect.Indirect(reflect.ValueOf(inv.Input))
+		if input.Kind() != reflect.Struct {
+			return ""
+		}
+		field := input.FieldByName(fieldname)
+		if !field.IsValid() {
+			return ""
+		}
+		return fmt.Sprint(reflect.Indirect(field).Interface())
+	}
+}
+
+// Token buckets allowing up to Limit requests per Period for each key,
+// refilled continuously. Its Intercept method rejects the requests over
+// the limit with a 429
+type RateLimiter struct {
+	Limit  int
+	Period time.Duration
+	Key    RateKey
+
+	// The least recently used buckets are dropped past that many keys,
+	// which resets the limit of their keys
+	MaxKeys int
+
+	lock    sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List
+}
+
+type tokenBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+var DefaultRateLimitKeys = 10000
+
+func NewRateLimiter(limit int, period time.Duration, key RateKey) *RateLimiter {
+	return &RateLimiter{Limit: limit, Period: period, Key: key, MaxKeys: DefaultRateLimitKeys}
+}
+
+// Takes a token from the bucket of the key. Returns how long to wait for
+// the next token if there is none left
+func (limiter *RateLimiter) Allow(key string) (bool, time.Duration) {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+	if limiter.Limit <= 0 || limiter.Period <= 0 {
+		return false, limiter.Period
+	}
+	if limiter.buckets == nil {
+		limiter.buckets = map[string]*list.Element{}
+		limiter.lru = list.New()
+	}
+	now := time.Now()
+	rate := float64(limiter.Limit) / float64(limiter.Period)
+
+	element, found := limiter.buckets[key]
+	if found {
+		limiter.lru.MoveToFront(element)
+	} else {
+		for limiter.MaxKeys > 0 && limiter.lru.Len() >= limiter.MaxKeys {
+			oldest := limiter.lru.Back()
+			limiter.lru.Remove(oldest)
+			delete(limiter.buckets, oldest.Value.(*tokenBucket).key)
+		}
+		element = limiter.lru.PushFront(&tokenBucket{key: key, tokens: float64(limiter.Limit), updated: now})
+		limiter.buckets[key] = element
+	}
+	bucket := element.Value.(*tokenBucket)
+
+	bucket.tokens = math.Min(float64(limiter.Limit), bucket.tokens+float64(now.Sub(bucket.updated))*rate)
+	bucket.updated = now
+	if bucket.tokens < 1 {
+		return false, time.Duration(math.Ceil((1 - bucket.tokens) / rate))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+func (limiter *RateLimiter) Intercept(inv *Invocation, next Invoker) (int, error) {
+	if allowed, wait := limiter.Allow(limiter.Key(inv)); !allowed {
+		err := ResourceExhausted("Rate limit of %d per %v exceeded", limiter.Limit, limiter.Period).WithRetryAfter(wait)
+		return err.Status, err
+	}
+	return next(inv)
+}
+
+// Limits each key to limit requests per period on this endpoint
+func (ep *Endpoint) RateLimit(limit int, period time.Duration, key RateKey) *Endpoint {
+	return ep.Intercept(NewRateLimiter(limit, period, key).Intercept)
+}
+
+// Limits each key to limit requests per period, across all the endpoints
+// of the router
+func (router *Router) RateLimit(limit int, period time.Duration, key RateKey) *Router {
+	return router.Intercept(NewRateLimiter(limit, period, key).Intercept)
+}
@@ -0,0 +1,152 @@
+package hermes_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type LimitedService struct{}
+
+type Login struct {
+	User string
+}
+
+func (_ LimitedService) SNI() string { return "UNUSED" }
+
+func (_ LimitedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Login", "POST", "/login", Login{}, nil).RateLimit(2, time.Hour, hermes.ByField("User")),
+		hermes.EP("Search", "GET", "/search", nil, nil).RateLimit(1, time.Hour, hermes.ByHeader("X-Api-Key")),
+	}
+}
+
+func (_ LimitedService) Login(ctx context.Context, in *Login) error { return nil }
+
+func (_ LimitedService) Search(ctx context.Context) error { return nil }
+
+func TestRateLimit(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(LimitedService{}).Serve(engine))
+	caller := hermes.NewCaller(LimitedService{})
+	caller.Client = &hermes.MockClient{engine}
+
+	for i := 0; i < 2; i++ {
+		code, err := caller.Call(context.Background(), "Login", &Login{"alice"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, code)
+	}
+	code, err := caller.Call(context.Background(), "Login", &Login{"alice"}, nil)
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.True(t, errors.Is(err, hermes.ResourceExhausted("")))
+	assert.InDelta(t, 1800, err.(*hermes.Error).RetryAfter, 1)
+
+	// Other keys have buckets of their own
+	code, err = caller.Call(context.Background(), "Login", &Login{"bob"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	search := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/search", nil)
+		req.Header.Set("X-Api-Key", key)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusNoContent, search("a").Code)
+	w := search("a")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusNoContent, search("b").Code)
+}
+
+func TestRouterRateLimit(t *testing.T) {
+	engine := gin.New()
+	router := hermes.NewRouter(ShapedService{}).RateLimit(1, time.Minute, hermes.ByClientIP)
+	require.NoError(t, router.Serve(engine))
+
+	// The limit is shared by the endpoints of the router
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("DELETE", "/delete/1", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	limiter := hermes.NewRateLimiter(1, 20*time.Millisecond, nil)
+	allowed, _ := limiter.Allow("key")
+	assert.True(t, allowed)
+	allowed, wait := limiter.Allow("key")
+	assert.False(t, allowed)
+	assert.True(t, wait > 0 && wait <= 20*time.Millisecond)
+
+	time.Sleep(wait)
+	allowed, _ = limiter.Allow("key")
+	assert.True(t, allowed)
+}
+
+func TestClientIPIgnoresForwardedHeaders(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(ShapedService{}).RateLimit(1, time.Hour, hermes.ByClientIP).Serve(engine))
+
+	codes := []int{}
+	for _, forwarded := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/ping", nil)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.Header.Set("X-Real-IP", forwarded)
+		engine.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{http.StatusNoContent, http.StatusTooManyRequests, http.StatusTooManyRequests}, codes)
+}
+
+func TestForwardedClientIP(t *testing.T) {
+	key := hermes.ByForwardedClientIP("10.0.0.0/8", "192.168.1.1")
+	forwarded := func(remote string, headers ...string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remote
+		for _, header := range headers {
+			req.Header.Add("X-Forwarded-For", header)
+		}
+		return key(&hermes.Invocation{Gin: &gin.Context{Request: req}})
+	}
+
+	assert.Equal(t, "1.2.3.4", forwarded("10.0.0.1:1234", "1.2.3.4"))
+	assert.Equal(t, "1.2.3.4", forwarded("10.0.0.1:1234", "6.6.6.6, 1.2.3.4, 192.168.1.1"))
+	assert.Equal(t, "1.2.3.4", forwarded("10.0.0.1:1234", "6.6.6.6", "1.2.3.4"))
+	assert.Equal(t, "10.0.0.1", forwarded("10.0.0.1:1234"))
+	// Untrusted peers cannot pick their key
+	assert.Equal(t, "5.5.5.5", forwarded("5.5.5.5:1234", "1.2.3.4"))
+	assert.Panics(t, func() { hermes.ByForwardedClientIP("not-an-ip") })
+}
+
+func TestRateLimiterMaxKeys(t *testing.T) {
+	limiter := hermes.NewRateLimiter(1, time.Hour, nil)
+	limiter.MaxKeys = 2
+
+	for _, key := range []string{"a", "b"} {
+		allowed, _ := limiter.Allow(key)
+		assert.True(t, allowed)
+	}
+	allowed, _ := limiter.Allow("a")
+	assert.False(t, allowed)
+
+	// b is the least recently used bucket, and gets evicted for c
+	allowed, _ = limiter.Allow("c")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("a")
+	assert.False(t, allowed)
+	allowed, _ = limiter.Allow("b")
+	assert.True(t, allowed)
+}
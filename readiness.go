@@ -0,0 +1,161 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const readinessKey = "Hermes-Readiness"
+
+var (
+	DefaultCheckTimeout  = 2 * time.Second
+	DefaultCheckCacheFor = 5 * time.Second
+)
+
+// A dependency the server needs to serve requests, like a database or a
+// downstream service. The result of a check is reused for CacheFor so that
+// probes do not hammer the dependencies
+type ReadinessCheck struct {
+	Name     string
+	Timeout  time.Duration
+	CacheFor time.Duration
+	Check    func(ctx context.Context) error
+
+	lock   sync.Mutex
+	result *CheckResult
+}
+
+func NewReadinessCheck(name string, check func(ctx context.Context) error) *ReadinessCheck {
+	return &ReadinessCheck{
+		Name:     name,
+		Timeout:  DefaultCheckTimeout,
+		CacheFor: DefaultCheckCacheFor,
+		Check:    check,
+	}
+}
+
+// Checks that the callable answers its health check
+func CallerCheck(name string, caller *Caller) *ReadinessCheck {
+	return NewReadinessCheck(name, func(ctx context.Context) error {
+		code, err := caller.Call(ctx, "Healthz", nil, nil)
+		if err == nil && code != http.StatusOK {
+			err = fmt.Errorf("Health check returned %d", code)
+		}
+		return err
+	})
+}
+
+type CheckResult struct {
+	Name      string
+	Ready     bool
+	Error     string `json:",omitempty"`
+	LatencyMS float64
+	CheckedAt time.Time
+}
+
+type ReadinessReport struct {
+	Ready  bool
+	Checks []CheckResult
+}
+
+// Runs the check unless its last result is still fresh. The result is
+// shared by every probe, so the check does not run under the context of
+// the probe that happened to trigger it
+func (check *ReadinessCheck) run() CheckResult {
+	check.lock.Lock()
+	defer check.lock.Unlock()
+	if check.result != nil && time.Since(check.result.CheckedAt) < check.CacheFor {
+		return *check.result
+	}
+
+	ctx := context.Background()
+	if check.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, check.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check.Check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("Check timed out after %v", time.Since(start))
+	}
+
+	result := &CheckResult{
+		Name:      check.Name,
+		Ready:     err == nil,
+		LatencyMS: float64(time.Since(start)) / float64(time.Millisecond),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	check.result = result
+	return *result
+}
+
+// Runs the checks concurrently
+func checkReadiness(checks []*ReadinessCheck) *ReadinessReport {
+	report := &ReadinessReport{Ready: true, Checks: make([]CheckResult, len(checks))}
+	wg := sync.WaitGroup{}
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check *ReadinessCheck) {
+			defer wg.Done()
+			report.Checks[i] = check.run()
+		}(i, check)
+	}
+	wg.Wait()
+	for _, result := range report.Checks {
+		report.Ready = report.Ready && result.Ready
+	}
+	return report
+}
+
+// Registers checks that must pass for /hermes/readyz to report 200
+func (router *Router) CheckReadiness(checks ...*ReadinessCheck) *Router {
+	router.ReadinessChecks = append(router.ReadinessChecks, checks...)
+	return router
+}
+
+// Reports 503 while a check fails or the server shuts down
+func (svc HealthChecker) Readyz(ctx context.Context, out *ReadinessReport) (int, error) {
+	checks, _ := ctx.Value(readinessKey).([]*ReadinessCheck)
+	*out = *checkReadiness(checks)
+	if Draining(ctx) {
+		out.Ready = false
+	}
+	if !out.Ready {
+		return http.StatusServiceUnavailable, nil
+	}
+	return http.StatusOK, nil
+}
+
+var Readyz = NewEndpoint("Readyz", "GET", "/hermes/readyz", nil, ReadinessReport{}).Public()
+
+// Polls the readiness endpoint of the callable every interval until it
+// reports 200 or the context is done
+func (caller *Caller) WaitReady(ctx context.Context, interval time.Duration) error {
+	for {
+		code, err := caller.Call(ctx, "Readyz", nil, &ReadinessReport{})
+		if err == nil && code == http.StatusOK {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("Readiness check returned %d", code)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s is not ready: %v", caller.callable.SNI(), err)
+		case <-time.After(interval):
+		}
+	}
+}
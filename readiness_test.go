@@ -0,0 +1,152 @@
+package hermes_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ReadyService struct {
+	hermes.HealthChecker
+}
+
+func (_ ReadyService) SNI() string { return "UNUSED" }
+
+func (_ ReadyService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.Healthz,
+		hermes.Readyz,
+	}
+}
+
+func getReadyz(engine *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/hermes/readyz", nil))
+	return w
+}
+
+func TestReadinessReport(t *testing.T) {
+	var down int32 = 1
+	var calls int32
+	database := hermes.NewReadinessCheck("database", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			return fmt.Errorf("Connection refused")
+		}
+		return nil
+	})
+	database.CacheFor = 0
+	slow := hermes.NewReadinessCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	slow.Timeout = 10 * time.Millisecond
+
+	engine := gin.New()
+	router := hermes.NewRouter(ReadyService{}).CheckReadiness(database)
+	require.NoError(t, router.Serve(engine))
+	caller := hermes.NewCaller(ReadyService{})
+	caller.Client = &hermes.MockClient{engine}
+
+	report := &hermes.ReadinessReport{}
+	code, err := caller.Call(context.Background(), "Readyz", nil, report)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	// Liveness does not depend on the checks
+	code, err = caller.Call(context.Background(), "Healthz", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+
+	atomic.StoreInt32(&down, 0)
+	code, err = caller.Call(context.Background(), "Readyz", nil, report)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.Ready)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "database", report.Checks[0].Name)
+	assert.True(t, report.Checks[0].Ready)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	engine = gin.New()
+	require.NoError(t, hermes.NewRouter(ReadyService{}).CheckReadiness(database, slow).Serve(engine))
+	w := getReadyz(engine)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "timed out")
+}
+
+func TestReadinessCaching(t *testing.T) {
+	var calls int32
+	check := hermes.NewReadinessCheck("cached", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	check.CacheFor = time.Hour
+
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(ReadyService{}).CheckReadiness(check).Serve(engine))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, getReadyz(engine).Code)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestWaitReady(t *testing.T) {
+	var ready int32
+	check := hermes.NewReadinessCheck("warmup", func(ctx context.Context) error {
+		if atomic.LoadInt32(&ready) == 0 {
+			return fmt.Errorf("Warming up")
+		}
+		return nil
+	})
+	check.CacheFor = 0
+
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(ReadyService{}).CheckReadiness(check).Serve(engine))
+	caller := hermes.NewCaller(ReadyService{})
+	caller.Client = &hermes.MockClient{engine}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Error(t, caller.WaitReady(ctx, 5*time.Millisecond))
+
+	time.AfterFunc(20*time.Millisecond, func() { atomic.StoreInt32(&ready, 1) })
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, caller.WaitReady(ctx, 5*time.Millisecond))
+}
+
+func TestReadinessIgnoresProbeContext(t *testing.T) {
+	var calls int32
+	database := hermes.NewReadinessCheck("database", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-time.After(20 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	engine := gin.New()
+	router := hermes.NewRouter(ReadyService{}).CheckReadiness(database)
+	router.DefaultTimeout = time.Second
+	require.NoError(t, router.Serve(engine))
+
+	// A probe that gives up early does not leave a failure for the next ones
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/hermes/readyz", nil).WithContext(ctx))
+
+	assert.Equal(t, http.StatusOK, getReadyz(engine).Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
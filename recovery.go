@@ -0,0 +1,45 @@
+package hermes
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PanicHandler func(ctx context.Context, ep *Endpoint, recovered interface{}, stack []byte)
+
+var DefaultPanicHandler PanicHandler = LogPanic
+
+func LogPanic(ctx context.Context, ep *Endpoint, recovered interface{}, stack []byte) {
+	LoggerFrom(ctx).Error("Handler panicked", "panic", recovered, "stack", string(stack))
+}
+
+// Must be deferred by the gin handler of the endpoint. Turns a panic into
+// a 500 response with the request id, and reports it to the panic handler
+func recoverPanic(router *Router, ctx *gin.Context, ep *Endpoint) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	if hp, ok := recovered.(*handlerPanic); ok {
+		respondPanic(router, ctx, ep, hp.recovered, hp.stack)
+		return
+	}
+	respondPanic(router, ctx, ep, recovered, debug.Stack())
+}
+
+func respondPanic(router *Router, ctx *gin.Context, ep *Endpoint, recovered interface{}, stack []byte) {
+	if router.PanicHandler != nil {
+		router.PanicHandler(ctx, ep, recovered, stack)
+	}
+
+	// Too late to change the response if the handler already wrote to it
+	if ctx.Writer.Written() {
+		return
+	}
+	rid := GetRequestID(ctx)
+	ctx.Header("Hermes-Request-ID", rid)
+	ctx.JSON(http.StatusInternalServerError, Internal("Internal server error [%s]", rid))
+}
@@ -0,0 +1,62 @@
+package hermes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type PanickingService struct{}
+
+func (_ PanickingService) SNI() string { return "UNUSED" }
+
+func (_ PanickingService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Panic", "GET", "/panic", nil, nil),
+	}
+}
+
+func (_ PanickingService) Panic(ctx context.Context) (int, error) {
+	panic("oops")
+}
+
+func TestPanicRecovery(t *testing.T) {
+	var panicked *hermes.Endpoint
+	var recovered interface{}
+	var rid string
+
+	engine := gin.New()
+	router := hermes.NewRouter(PanickingService{})
+	router.PanicHandler = func(ctx context.Context, ep *hermes.Endpoint, r interface{}, stack []byte) {
+		panicked, recovered, rid = ep, r, hermes.GetRequestID(ctx)
+		assert.Contains(t, string(stack), "PanickingService")
+	}
+	require.NoError(t, router.Serve(engine))
+
+	caller := hermes.NewCaller(PanickingService{})
+	caller.Client = &hermes.MockClient{engine}
+	ctx := hermes.SetRequestID(context.Background(), "my-request")
+	code, err := caller.Call(ctx, "Panic", nil, nil)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "my-request")
+
+	require.NotNil(t, panicked)
+	assert.Equal(t, "Panic", panicked.Handler)
+	assert.Equal(t, "oops", recovered)
+	assert.Equal(t, "my-request", rid)
+
+	// The request id is also returned as a header
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set("Hermes-Request-ID", "other-request")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "other-request", w.Header().Get("Hermes-Request-ID"))
+}
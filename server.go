@@ -1,34 +1,131 @@
 package hermes
 
 import (
-	"fmt"
+	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Struct wrappers
 type Router struct {
-	Bindings BindingFactory
+	Bindings     BindingFactory
+	Encoders     []Encoder
+	Interceptors []Interceptor
+	PanicHandler PanicHandler
 
-	server Server
+	// Requests to endpoints that are not public must be authenticated by
+	// one of them, if there are any
+	Authenticators []Authenticator
+
+	// Requests to endpoints that are not public must be signed if set,
+	// whatever other credentials they have
+	Signatures *SignatureVerifier
+
+	// Lets browsers call the endpoints from other origins if set
+	CORS *CORSPolicy
+
+	// Outputs at least that large get compressed, 0 disables compression
+	CompressionThreshold int
+
+	// Applies to the endpoints that do not set their own timeout
+	DefaultTimeout time.Duration
+
+	// Applies to the endpoints that do not set their own body limit
+	DefaultMaxBodySize int64
+	JSONLimits         JSONLimits
+
+	// Reported by /hermes/readyz, separately from the liveness of /hermes/healthz
+	ReadinessChecks []*ReadinessCheck
+
+	// Records the metrics of the endpoints if set. Every router starts with
+	// a registry of its own
+	Metrics *MetricRegistry
+
+	// Traces the requests if set
+	Tracer Tracer
+
+	// Handlers get a logger derived from it with LoggerFrom
+	Logger Logger
+
+	server   Server
+	draining int32
 }
 
 func NewRouter(server Server) *Router {
-	router := &Router{DefaultBindingFactory, server}
+	router := &Router{server: server}
+	router.Bindings = DefaultBindingFactory
+	router.Encoders = DefaultEncoders
+	router.PanicHandler = DefaultPanicHandler
+	router.CompressionThreshold = DefaultCompressionThreshold
+	router.DefaultMaxBodySize = DefaultMaxBodySize
+	router.JSONLimits = DefaultJSONLimits
+	router.Metrics = NewMetricRegistry()
+	router.Logger = DefaultLogger
+	return router
+}
+
+// Adds interceptors around every handler of the router. They run before
+// the interceptors of the endpoints themselves
+func (router *Router) Intercept(interceptors ...Interceptor) *Router {
+	router.Interceptors = append(router.Interceptors, interceptors...)
+	return router
+}
+
+func (router *Router) Authenticate(authenticators ...Authenticator) *Router {
+	router.Authenticators = append(router.Authenticators, authenticators...)
+	return router
+}
+
+func (router *Router) RequireSignatures(verifier *SignatureVerifier) *Router {
+	router.Signatures = verifier
 	return router
 }
 
 func (router *Router) Serve(engine *gin.Engine) error {
+	if err := checkEndpoints(router.server); err != nil {
+		return err
+	}
+	if router.CORS != nil {
+		if err := router.CORS.validate(); err != nil {
+			return err
+		}
+	}
+
 	handlerType := reflect.TypeOf(router.server)
 	for _, ep := range router.server.Endpoints() {
-		method, ok := handlerType.MethodByName(ep.Handler)
-		if !ok {
-			return fmt.Errorf("Endpoint '%s' does not match any method of the type %v", ep.Handler, handlerType)
-		}
+		method, _ := handlerType.MethodByName(ep.Handler)
+		shape, _ := checkSignature(ep, method)
 		binding := router.Bindings(ep.Params, ep.Queries, ep.Headers)
-		fn := getGinHandler(router.server, binding, ep, method)
-		engine.Handle(ep.Method, ep.Path, fn)
+		fn := getGinHandler(router, binding, ep, method, shape)
+		engine.Handle(ep.Method, fullPath(router.server, ep), fn)
+	}
+	if router.CORS != nil {
+		router.servePreflights(engine)
 	}
 	return nil
 }
+
+// Serves the endpoints as a plain http.Handler, so that the service can be
+// mounted in an http.ServeMux or any other framework. The handler routes
+// requests with an engine of its own and panics if the EndpointMap is
+// invalid, like http.ServeMux does with invalid patterns
+func (router *Router) Handler() http.Handler {
+	engine, err := router.engine()
+	if err != nil {
+		panic(err)
+	}
+	return engine
+}
+
+func (router *Router) engine() (*gin.Engine, error) {
+	engine := gin.New()
+	if err := router.Serve(engine); err != nil {
+		return nil, err
+	}
+	engine.NoRoute(func(ctx *gin.Context) {
+		ctx.JSON(http.StatusNotFound, NotFound("No endpoint for %s %s", ctx.Request.Method, ctx.Request.URL.Path))
+	})
+	return engine, nil
+}
@@ -0,0 +1,118 @@
+package hermes_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ShapedService struct{}
+
+func (_ ShapedService) SNI() string { return "UNUSED" }
+
+func (_ ShapedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Lookup", "GET", "/lookup/:action", Action{}, Outbound{}).Param("action"),
+		hermes.EP("Delete", "DELETE", "/delete/:action", Action{}, nil).Param("action"),
+		hermes.EP("Ping", "GET", "/ping", nil, nil),
+	}
+}
+
+func (_ ShapedService) Lookup(ctx context.Context, in *Action) (*Outbound, error) {
+	switch in.Action {
+	case 0:
+		return nil, nil
+	case 1:
+		return &Outbound{Ok: true}, nil
+	case 2:
+		return nil, hermes.NotFound("No action %d", in.Action)
+	}
+	return nil, errors.New("Lookup failed")
+}
+
+func (_ ShapedService) Delete(ctx context.Context, in *Action) error {
+	if in.Action != 1 {
+		return hermes.PermissionDenied("Cannot delete %d", in.Action)
+	}
+	return nil
+}
+
+func (_ ShapedService) Ping(ctx context.Context) error { return nil }
+
+func TestHandlerShapes(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(ShapedService{}).Serve(engine))
+	caller := hermes.NewCaller(ShapedService{})
+	caller.Client = &hermes.MockClient{engine}
+
+	out := &Outbound{}
+	code, err := caller.Call(context.Background(), "Lookup", &Action{1}, out)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, out.Ok)
+
+	code, err = caller.Call(context.Background(), "Lookup", &Action{0}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	// The output is left untouched when there is none
+	out = &Outbound{Ok: true}
+	code, err = caller.Call(context.Background(), "Lookup", &Action{0}, out)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.True(t, out.Ok)
+
+	code, err = caller.Call(context.Background(), "Lookup", &Action{2}, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.True(t, errors.Is(err, hermes.NotFound("")))
+
+	code, err = caller.Call(context.Background(), "Lookup", &Action{3}, nil)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.EqualError(t, err, "Lookup failed")
+
+	code, err = caller.Call(context.Background(), "Delete", &Action{1}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, err = caller.Call(context.Background(), "Delete", &Action{2}, nil)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Error(t, err)
+
+	code, err = caller.Call(context.Background(), "Ping", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+}
+
+type MisshapedService struct{}
+
+func (_ MisshapedService) SNI() string { return "UNUSED" }
+
+func (_ MisshapedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("NoOutput", "GET", "/nooutput", nil, Outbound{}),
+		hermes.EP("WrongOutput", "GET", "/wrongoutput", nil, Outbound{}),
+		hermes.EP("Unexpected", "GET", "/unexpected", nil, nil),
+	}
+}
+
+func (_ MisshapedService) NoOutput(ctx context.Context) error                { return nil }
+func (_ MisshapedService) WrongOutput(ctx context.Context) (*Inbound, error) { return nil, nil }
+func (_ MisshapedService) Unexpected(ctx context.Context) (*Outbound, error) { return nil, nil }
+
+func TestHandlerShapesChecked(t *testing.T) {
+	err := hermes.NewRouter(MisshapedService{}).Serve(gin.New())
+	require.Error(t, err)
+	for _, fragment := range []string{
+		"Handler 'NoOutput' should return (*hermes_test.Outbound, error)",
+		"Handler 'WrongOutput' should return (*hermes_test.Outbound, error)",
+		"Handler 'Unexpected' should not return an output",
+	} {
+		assert.Contains(t, err.Error(), fragment)
+	}
+}
@@ -0,0 +1,187 @@
+package hermes
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader        = "Hermes-Signature"
+	SignatureKeyIDHeader   = "Hermes-Signature-Key"
+	SignatureTimeHeader    = "Hermes-Signature-Time"
+	SignatureNonceHeader   = "Hermes-Signature-Nonce"
+	SignatureHeadersHeader = "Hermes-Signature-Headers"
+)
+
+// How far the timestamp of signed requests can be from the clock of the
+// server
+var DefaultSignatureSkew = 5 * time.Minute
+
+// Signs the method, path, query, body and the given headers of requests
+// with a shared key, along with a timestamp and a nonce
+type RequestSigner struct {
+	KeyID   string
+	Key     []byte
+	Headers []string
+}
+
+func NewRequestSigner(keyid string, key []byte, headers ...string) *RequestSigner {
+	return &RequestSigner{KeyID: keyid, Key: key, Headers: headers}
+}
+
+func (signer *RequestSigner) Sign(req *http.Request) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	headers := []string{}
+	for _, header := range signer.Headers {
+		headers = append(headers, strings.ToLower(header))
+	}
+	sort.Strings(headers)
+
+	req.Header.Set(SignatureKeyIDHeader, signer.KeyID)
+	req.Header.Set(SignatureTimeHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set(SignatureNonceHeader, hex.EncodeToString(nonce))
+	req.Header.Set(SignatureHeadersHeader, strings.Join(headers, ";"))
+	req.Header.Set(SignatureHeader, signature(signer.Key, req, headers, body))
+	return nil
+}
+
+// Verifies the signatures of requests, rejecting stale timestamps and
+// nonces that were already used. Routers run it before their
+// authenticators, so that no other credentials can stand in for a
+// signature
+type SignatureVerifier struct {
+	Keys    map[string][]byte
+	MaxSkew time.Duration
+
+	lock   sync.Mutex
+	nonces map[string]time.Time
+	pruned time.Time
+}
+
+func NewSignatureVerifier(keys map[string][]byte) *SignatureVerifier {
+	return &SignatureVerifier{Keys: keys, MaxSkew: DefaultSignatureSkew}
+}
+
+// Returns the principal of signed requests, with the key id as its subject
+func (verifier *SignatureVerifier) Verify(req *http.Request) (*Principal, error) {
+	signed := req.Header.Get(SignatureHeader)
+	if signed == "" {
+		return nil, Unauthenticated("Missing signature")
+	}
+
+	keyid := req.Header.Get(SignatureKeyIDHeader)
+	key, found := verifier.Keys[keyid]
+	if !found {
+		return nil, Unauthenticated("Unknown signature key '%s'", keyid)
+	}
+	timestamp, err := strconv.ParseInt(req.Header.Get(SignatureTimeHeader), 10, 64)
+	if err != nil {
+		return nil, Unauthenticated("Malformed signature timestamp")
+	}
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew > verifier.MaxSkew || skew < -verifier.MaxSkew {
+		return nil, Unauthenticated("Stale signature timestamp")
+	}
+	nonce := req.Header.Get(SignatureNonceHeader)
+	if nonce == "" {
+		return nil, Unauthenticated("Missing signature nonce")
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	headers := []string{}
+	if signedheaders := req.Header.Get(SignatureHeadersHeader); signedheaders != "" {
+		headers = strings.Split(signedheaders, ";")
+	}
+	if !hmac.Equal([]byte(signed), []byte(signature(key, req, headers, body))) {
+		return nil, Unauthenticated("Invalid signature")
+	}
+
+	// Nonces only need to be remembered while their timestamp is valid
+	if !verifier.useNonce(keyid+":"+nonce, time.Unix(timestamp, 0).Add(verifier.MaxSkew)) {
+		return nil, Unauthenticated("Replayed signature")
+	}
+	return &Principal{Subject: keyid, Scheme: SignatureHeader}, nil
+}
+
+func (verifier *SignatureVerifier) useNonce(nonce string, expiry time.Time) bool {
+	verifier.lock.Lock()
+	defer verifier.lock.Unlock()
+	if verifier.nonces == nil {
+		verifier.nonces = map[string]time.Time{}
+	}
+
+	now := time.Now()
+	if expires, found := verifier.nonces[nonce]; found && now.Before(expires) {
+		return false
+	}
+	if now.Sub(verifier.pruned) > time.Second {
+		for used, expires := range verifier.nonces {
+			if !now.Before(expires) {
+				delete(verifier.nonces, used)
+			}
+		}
+		verifier.pruned = now
+	}
+	verifier.nonces[nonce] = expiry
+	return true
+}
+
+// Reads the body of the request and puts it back for the next reader
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return []byte{}, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+func signature(key []byte, req *http.Request, headers []string, body []byte) string {
+	bodyhash := sha256.Sum256(body)
+	lines := []string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		req.Header.Get(SignatureKeyIDHeader),
+		req.Header.Get(SignatureTimeHeader),
+		req.Header.Get(SignatureNonceHeader),
+	}
+	for _, header := range headers {
+		lines = append(lines, header+":"+strings.TrimSpace(req.Header.Get(header)))
+	}
+	lines = append(lines, hex.EncodeToString(bodyhash[:]))
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,124 @@
+package hermes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type SignedService struct{}
+
+func (_ SignedService) SNI() string { return "signed" }
+
+func (_ SignedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Transfer", "POST", "/transfer/:action", Action{}, Inbound{}).Param("action").Query("action"),
+		hermes.EP("Quote", "GET", "/quote", nil, nil).Public(),
+	}
+}
+
+func (_ SignedService) Quote(ctx context.Context) error {
+	return nil
+}
+
+func (_ SignedService) Transfer(ctx context.Context, in *Action) (*Inbound, error) {
+	return &Inbound{hermes.PrincipalFrom(ctx).Subject}, nil
+}
+
+func TestRequestSigning(t *testing.T) {
+	key := []byte("shared key")
+	engine := gin.New()
+	router := hermes.NewRouter(SignedService{}).RequireSignatures(hermes.NewSignatureVerifier(map[string][]byte{"billing": key}))
+	require.NoError(t, router.Serve(engine))
+
+	caller := hermes.NewCaller(SignedService{})
+	caller.Client = &hermes.MockClient{engine}
+	caller.Signer = hermes.NewRequestSigner("billing", key, "Content-Type")
+
+	// The key id is available to handlers
+	out := &Inbound{}
+	code, err := caller.Call(context.Background(), "Transfer", &Action{42}, out)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "billing", out.Message)
+
+	sign := func(signer *hermes.RequestSigner, path string) *http.Request {
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("Content-Type", "application/json")
+		require.NoError(t, signer.Sign(req))
+		return req
+	}
+	serve := func(req *http.Request) int {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	req := sign(caller.Signer, "/transfer/1?action=1")
+	replay := req.Clone(context.Background())
+	assert.Equal(t, http.StatusOK, serve(req))
+	assert.Equal(t, http.StatusUnauthorized, serve(replay))
+
+	// Tampering with signed parts of the request breaks the signature
+	for _, tamper := range []func(req *http.Request){
+		func(req *http.Request) { req.URL.RawQuery = "action=2" },
+		func(req *http.Request) { req.Header.Set("Content-Type", "text/plain") },
+		func(req *http.Request) { req.Method = "PUT" },
+		func(req *http.Request) {
+			req.Header.Set(hermes.SignatureTimeHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+		},
+	} {
+		req := sign(caller.Signer, "/transfer/1?action=1")
+		tamper(req)
+		assert.NotEqual(t, http.StatusOK, serve(req))
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(sign(hermes.NewRequestSigner("billing", []byte("wrong key")), "/transfer/1")))
+	assert.Equal(t, http.StatusUnauthorized, serve(sign(hermes.NewRequestSigner("unknown", key), "/transfer/1")))
+	assert.Equal(t, http.StatusUnauthorized, serve(httptest.NewRequest("POST", "/transfer/1", nil)))
+}
+
+func TestSignaturesWithAuthenticators(t *testing.T) {
+	key := []byte("shared key")
+	engine := gin.New()
+	router := hermes.NewRouter(SignedService{}).
+		RequireSignatures(hermes.NewSignatureVerifier(map[string][]byte{"billing": key})).
+		Authenticate(hermes.BearerAuth(func(token string) (*hermes.Principal, error) {
+			return &hermes.Principal{Subject: token, Scheme: "Bearer"}, nil
+		}))
+	require.NoError(t, router.Serve(engine))
+
+	caller := hermes.NewCaller(SignedService{})
+	caller.Client = &hermes.MockClient{engine}
+	caller.Credentials = hermes.BearerCredentials(map[string]string{"signed": "alice"})
+
+	// Valid credentials do not stand in for the signature
+	code, err := caller.Call(context.Background(), "Transfer", &Action{42}, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.EqualError(t, err, "Missing signature")
+
+	// Signed requests still need credentials, which make the principal
+	out := &Inbound{}
+	caller.Signer = hermes.NewRequestSigner("billing", key)
+	code, err = caller.Call(context.Background(), "Transfer", &Action{42}, out)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "alice", out.Message)
+
+	caller.Credentials = nil
+	code, _ = caller.Call(context.Background(), "Transfer", &Action{42}, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Public endpoints are open to anyone, signatures included
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/quote", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
@@ -0,0 +1,125 @@
+package hermes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The wire format of a streaming endpoint, also used as its content type
+type StreamFormat string
+
+const (
+	NDJSON StreamFormat = "application/x-ndjson"
+	SSE    StreamFormat = "text/event-stream"
+)
+
+// Trailer holding the error of an NDJSON stream that failed after its
+// first item was sent. SSE streams send an "error" event instead
+const StreamErrorTrailer = "Hermes-Stream-Error"
+
+// In-band errors carry the status the handler failed with, since the
+// response already went out with a 200
+type streamError struct {
+	*Error
+	Status int
+}
+
+// Writes the items sent by a streaming handler to the response, flushing
+// after each one of them
+type streamWriter struct {
+	ctx    *gin.Context
+	format StreamFormat
+
+	lock     sync.Mutex
+	started  bool
+	finished bool
+}
+
+func newStreamWriter(ctx *gin.Context, format StreamFormat) *streamWriter {
+	return &streamWriter{ctx: ctx, format: format}
+}
+
+// Returns the send function given to the handler, of type func(*OutputType) error
+func (w *streamWriter) sender(outputType reflect.Type) interface{} {
+	fntype := reflect.FuncOf([]reflect.Type{reflect.PtrTo(outputType)}, []reflect.Type{errorType}, false)
+	fn := reflect.MakeFunc(fntype, func(args []reflect.Value) []reflect.Value {
+		err := w.send(args[0].Interface())
+		if err == nil {
+			return []reflect.Value{reflect.Zero(errorType)}
+		}
+		return []reflect.Value{reflect.ValueOf(&err).Elem()}
+	})
+	return fn.Interface()
+}
+
+func (w *streamWriter) start(code int) {
+	header := w.ctx.Writer.Header()
+	header.Set("Content-Type", string(w.format))
+	header.Set("Cache-Control", "no-cache")
+	if w.format == NDJSON {
+		header.Set("Trailer", StreamErrorTrailer)
+	}
+	w.ctx.Writer.WriteHeader(code)
+	w.ctx.Writer.Flush()
+	w.started = true
+}
+
+func (w *streamWriter) send(item interface{}) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.finished {
+		return fmt.Errorf("Stream is already closed")
+	}
+	if err := w.ctx.Request.Context().Err(); err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal stream item: %v", err)
+	}
+	if !w.started {
+		w.start(http.StatusOK)
+	}
+
+	if w.format == SSE {
+		_, err = fmt.Fprintf(w.ctx.Writer, "data: %s\n\n", content)
+	} else {
+		_, err = fmt.Fprintf(w.ctx.Writer, "%s\n", content)
+	}
+	if err != nil {
+		return err
+	}
+	w.ctx.Writer.Flush()
+	return nil
+}
+
+// Ends the stream once the handler returned. The error is reported like
+// for any other endpoint if nothing was sent yet, in-band otherwise
+func (w *streamWriter) finish(code int, e *Error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.finished = true
+	if !w.started && e != nil {
+		writeError(w.ctx, code, e)
+		return
+	} else if !w.started {
+		w.start(code)
+		return
+	} else if e == nil {
+		return
+	}
+
+	content, _ := json.Marshal(streamError{e, code})
+	if w.format == SSE {
+		fmt.Fprintf(w.ctx.Writer, "event: error\ndata: %s\n\n", content)
+		w.ctx.Writer.Flush()
+	} else {
+		w.ctx.Writer.Header().Set(StreamErrorTrailer, string(content))
+	}
+}
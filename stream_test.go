@@ -0,0 +1,133 @@
+package hermes_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type StreamingService struct{}
+
+type Progress struct {
+	Step  int
+	Total int
+}
+
+func (_ StreamingService) SNI() string { return "UNUSED" }
+
+func (_ StreamingService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Lines", "GET", "/lines", Action{}, Progress{}).Query("action").Stream(hermes.NDJSON),
+		hermes.EP("Events", "GET", "/events", Action{}, Progress{}).Query("action").Stream(hermes.SSE),
+	}
+}
+
+// Sends <in.Action> progress items, failing after the first one if negative
+func (_ StreamingService) Lines(ctx context.Context, in *Action, send func(*Progress) error) (int, error) {
+	return progress(in.Action, send)
+}
+
+func (_ StreamingService) Events(ctx context.Context, in *Action, send func(*Progress) error) (int, error) {
+	return progress(in.Action, send)
+}
+
+func progress(total int, send func(*Progress) error) (int, error) {
+	if total == 0 {
+		return http.StatusBadRequest, fmt.Errorf("Nothing to do")
+	} else if total == -2 {
+		send(&Progress{1, total})
+		return 0, hermes.NotFound("Step 2 is gone")
+	} else if total < 0 {
+		send(&Progress{1, total})
+		return http.StatusInternalServerError, fmt.Errorf("Failed after step 1")
+	}
+	for i := 1; i <= total; i++ {
+		if err := send(&Progress{i, total}); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+func streamingCaller(t *testing.T) (*hermes.Caller, *gin.Engine) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(StreamingService{}).Serve(engine))
+	caller := hermes.NewCaller(StreamingService{})
+	caller.Client = &hermes.MockClient{engine}
+	return caller, engine
+}
+
+func collect(caller *hermes.Caller, handler string, total int) ([]Progress, int, error) {
+	items := []Progress{}
+	code, err := caller.Stream(context.Background(), handler, &Action{total}, func(item interface{}) error {
+		items = append(items, *item.(*Progress))
+		return nil
+	})
+	return items, code, err
+}
+
+func TestStream(t *testing.T) {
+	caller, _ := streamingCaller(t)
+	for _, handler := range []string{"Lines", "Events"} {
+		items, code, err := collect(caller, handler, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []Progress{{1, 3}, {2, 3}, {3, 3}}, items)
+
+		// Errors before the first item are regular error responses
+		items, code, err = collect(caller, handler, 0)
+		assert.EqualError(t, err, "Nothing to do")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Empty(t, items)
+
+		// Errors after the first item are sent in-band
+		items, code, err = collect(caller, handler, -1)
+		assert.EqualError(t, err, "Failed after step 1")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []Progress{{1, -1}}, items)
+		require.IsType(t, &hermes.Error{}, err)
+		assert.Equal(t, http.StatusInternalServerError, err.(*hermes.Error).Status)
+
+		// In-band errors keep the status of the handler
+		items, code, err = collect(caller, handler, -2)
+		assert.True(t, errors.Is(err, hermes.NotFound("")))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []Progress{{1, -2}}, items)
+		require.IsType(t, &hermes.Error{}, err)
+		assert.Equal(t, http.StatusNotFound, err.(*hermes.Error).Status)
+	}
+}
+
+func TestStreamOverHTTP(t *testing.T) {
+	caller, engine := streamingCaller(t)
+	server := httptest.NewServer(engine)
+	defer server.Close()
+	caller.Client = hermes.DefaultClient
+	caller.Resolve = func(_, path string) (string, error) {
+		return strings.TrimPrefix(server.URL, "http://") + path, nil
+	}
+
+	items, _, err := collect(caller, "Lines", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Progress{{1, 2}, {2, 2}}, items)
+
+	items, _, err = collect(caller, "Lines", -2)
+	assert.EqualError(t, err, "Step 2 is gone")
+	assert.Equal(t, []Progress{{1, -2}}, items)
+	require.IsType(t, &hermes.Error{}, err)
+	assert.Equal(t, http.StatusNotFound, err.(*hermes.Error).Status)
+
+	resp, err := http.Get(server.URL + "/events?action=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+}
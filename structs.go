@@ -10,6 +10,14 @@ type Server interface {
 	Endpoints() EndpointMap
 }
 
+// Servers that implement this interface have all of their endpoints
+// mounted under the base path, both by the Router and the Caller. That
+// includes the endpoints of hermes like Healthz, Readyz and Metrics, which
+// end up at <base path>/hermes/healthz and so on
+type BasePather interface {
+	BasePath() string
+}
+
 // Aliases
 type EndpointMap []*Endpoint
 
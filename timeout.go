@@ -0,0 +1,214 @@
+package hermes
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A panic that happened in the goroutine of a handler running with a
+// timeout, carried over to the goroutine of the request
+type handlerPanic struct {
+	recovered interface{}
+	stack     []byte
+}
+
+type invocationResult struct {
+	code int
+	err  error
+	*handlerPanic
+}
+
+// Cancels the context of the invocation after the timeout. If the handler
+// has not returned by then the request fails with a 504 and the result of
+// the handler is discarded once it returns. The handler runs detached from
+// the gin context, which gin reuses for other requests once this one is
+// answered
+func timeoutInvoker(invoke Invoker, timeout time.Duration) Invoker {
+	return func(inv *Invocation) (int, error) {
+		ctx, cancel := context.WithTimeout(inv.Gin.Request.Context(), timeout)
+		defer cancel()
+
+		writer := &expiringWriter{ResponseWriter: inv.Gin.Writer, ctx: ctx}
+		detached := inv.Gin.Copy()
+		detached.Writer = writer
+		detached.Request = detached.Request.WithContext(ctx)
+		handlerInv := &Invocation{
+			Context:  detachedContext{ctx, detached},
+			Gin:      detached,
+			Endpoint: inv.Endpoint,
+			Input:    inv.Input,
+			Output:   inv.Output,
+		}
+
+		done := make(chan invocationResult, 1)
+		go func() {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					done <- invocationResult{handlerPanic: &handlerPanic{recovered, debug.Stack()}}
+				}
+			}()
+			code, err := invoke(handlerInv)
+			done <- invocationResult{code: code, err: err}
+		}()
+
+		select {
+		case res := <-done:
+			if res.handlerPanic != nil {
+				panic(res.handlerPanic)
+			}
+			inv.Output = handlerInv.Output
+			return res.code, res.err
+		case <-ctx.Done():
+			writer.expire()
+			return http.StatusGatewayTimeout, DeadlineExceeded("Handler %s timed out after %v", inv.Endpoint.Handler, timeout)
+		}
+	}
+}
+
+// The context of handlers running with a timeout: the deadline of the
+// request with the values the router set on the gin context
+type detachedContext struct {
+	context.Context
+	values *gin.Context
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	if name, ok := key.(string); ok {
+		if value, found := ctx.values.Get(name); found {
+			return value
+		}
+	}
+	return ctx.Context.Value(key)
+}
+
+// Lets handlers that take the gin context write the response until their
+// context is done. Writes are dropped after that
+type expiringWriter struct {
+	gin.ResponseWriter
+	ctx     context.Context
+	lock    sync.Mutex
+	expired bool
+}
+
+// Waits for the writes in progress, the response is not written to after
+func (w *expiringWriter) expire() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.expired = true
+}
+
+func (w *expiringWriter) done() bool {
+	if !w.expired && w.ctx.Err() != nil {
+		w.expired = true
+	}
+	return w.expired
+}
+
+func (w *expiringWriter) Header() http.Header {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.done() {
+		return http.Header{}
+	}
+	return w.ResponseWriter.Header()
+}
+
+func (w *expiringWriter) Write(data []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.done() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *expiringWriter) WriteString(s string) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.done() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *expiringWriter) WriteHeader(code int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.done() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *expiringWriter) WriteHeaderNow() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.done() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *expiringWriter) Flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.done() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *expiringWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.done() {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *expiringWriter) CloseNotify() <-chan bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.done() {
+		return make(chan bool)
+	}
+	return w.ResponseWriter.CloseNotify()
+}
+
+func (w *expiringWriter) Pusher() http.Pusher {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.done() {
+		return nil
+	}
+	return w.ResponseWriter.Pusher()
+}
+
+func (w *expiringWriter) Status() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.done() {
+		return http.StatusGatewayTimeout
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *expiringWriter) Size() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.done() {
+		return -1
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *expiringWriter) Written() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.done() || w.ResponseWriter.Written()
+}
@@ -0,0 +1,128 @@
+package hermes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type SlowService struct {
+	cancelled chan error
+}
+
+func (_ SlowService) SNI() string { return "UNUSED" }
+
+func (_ SlowService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Slow", "GET", "/slow", nil, nil).Timeout(10 * time.Millisecond),
+		hermes.EP("Fast", "GET", "/fast", nil, nil),
+	}
+}
+
+func (s SlowService) Slow(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		s.cancelled <- ctx.Err()
+	case <-time.After(time.Second):
+		s.cancelled <- nil
+	}
+	return http.StatusOK, nil
+}
+
+func (s SlowService) Fast(ctx context.Context) (int, error) {
+	return http.StatusOK, nil
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	svc := SlowService{make(chan error, 1)}
+	engine := gin.New()
+	router := hermes.NewRouter(svc)
+	router.DefaultTimeout = time.Second
+	require.NoError(t, router.Serve(engine))
+
+	caller := hermes.NewCaller(svc)
+	caller.Client = &hermes.MockClient{engine}
+
+	code, err := caller.Call(context.Background(), "Slow", nil, nil)
+	assert.Equal(t, http.StatusGatewayTimeout, code)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+	assert.Equal(t, context.DeadlineExceeded, <-svc.cancelled)
+
+	code, err = caller.Call(context.Background(), "Fast", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+type OverrunService struct {
+	done chan error
+}
+
+func (_ OverrunService) SNI() string { return "UNUSED" }
+
+func (_ OverrunService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Overrun", "GET", "/overrun", nil, nil).Timeout(10 * time.Millisecond),
+		hermes.EP("OverrunGin", "GET", "/overrun-gin", nil, nil).Timeout(10 * time.Millisecond),
+		hermes.EP("Fast", "GET", "/fast", nil, nil),
+	}
+}
+
+// Keeps using its context after the request timed out
+func (s OverrunService) Overrun(ctx context.Context) (int, error) {
+	<-ctx.Done()
+	for i := 0; i < 100; i++ {
+		hermes.LoggerFrom(ctx)
+		hermes.GetRequestID(ctx)
+		time.Sleep(time.Millisecond)
+	}
+	s.done <- ctx.Err()
+	return http.StatusOK, nil
+}
+
+func (s OverrunService) OverrunGin(ctx *gin.Context) (int, error) {
+	<-ctx.Request.Context().Done()
+	for i := 0; i < 100; i++ {
+		ctx.Header("X-Late", "true")
+		ctx.Writer.WriteString("late")
+		hermes.GetRequestID(ctx)
+		time.Sleep(time.Millisecond)
+	}
+	s.done <- ctx.Request.Context().Err()
+	return http.StatusOK, nil
+}
+
+func (s OverrunService) Fast(ctx context.Context) (int, error) {
+	return http.StatusOK, nil
+}
+
+// Run with -race: handlers that outlive their timeout must not touch the
+// gin context, which serves other requests by then
+func TestHandlerOverrun(t *testing.T) {
+	svc := OverrunService{make(chan error, 2)}
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(svc).Serve(engine))
+
+	for _, path := range []string{"/overrun", "/overrun-gin"} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		assert.NotContains(t, w.Body.String(), "late")
+		assert.Empty(t, w.Header().Get("X-Late"))
+	}
+	for i := 0; i < 50; i++ {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-Late"))
+	}
+	assert.Equal(t, context.DeadlineExceeded, <-svc.done)
+	assert.Equal(t, context.DeadlineExceeded, <-svc.done)
+}
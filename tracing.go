@@ -0,0 +1,251 @@
+package hermes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The span of the current request or call is in the context under that key
+const spanKey = "Hermes-Span"
+
+type SpanKind string
+
+const (
+	ServerSpan SpanKind = "server"
+	ClientSpan SpanKind = "client"
+)
+
+// Identifies a span across services, as carried by the W3C traceparent and
+// tracestate headers
+type SpanContext struct {
+	TraceID    string
+	SpanID     string
+	Sampled    bool
+	TraceState string `json:",omitempty"`
+}
+
+func (sc SpanContext) Valid() bool {
+	return len(sc.TraceID) == 32 && len(sc.SpanID) == 16
+}
+
+// Formats the span context as a traceparent header
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// Parses the traceparent header, the span context is invalid if the header
+// is missing or malformed
+func ParseTraceparent(traceparent, tracestate string) SpanContext {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return SpanContext{}
+	}
+	if (parts[0] == "00" && len(parts) != 4) || !isHexID(parts[1], 32) || !isHexID(parts[2], 16) {
+		return SpanContext{}
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return SpanContext{}
+	}
+	return SpanContext{
+		TraceID:    parts[1],
+		SpanID:     parts[2],
+		Sampled:    flags[0]&1 == 1,
+		TraceState: tracestate,
+	}
+}
+
+// IDs are lowercase hex and cannot be all zeroes
+func isHexID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil && strings.ToLower(id) == id
+}
+
+func extractSpanContext(header http.Header) SpanContext {
+	return ParseTraceparent(header.Get("traceparent"), header.Get("tracestate"))
+}
+
+func injectSpanContext(header http.Header, sc SpanContext) {
+	header.Set("traceparent", sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set("tracestate", sc.TraceState)
+	} else {
+		header.Del("tracestate")
+	}
+}
+
+func newID(bytes int) string {
+	id := make([]byte, bytes)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+type Span struct {
+	Name       string
+	Kind       SpanKind
+	Context    SpanContext
+	ParentID   string `json:",omitempty"`
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{} `json:",omitempty"`
+
+	lock   sync.Mutex
+	finish func(*Span)
+}
+
+// Returns the span of the request or call in the context, nil if there is
+// none
+func SpanFrom(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+func (span *Span) SetAttribute(key string, value interface{}) {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	if span.Attributes == nil {
+		span.Attributes = map[string]interface{}{}
+	}
+	span.Attributes[key] = value
+}
+
+// Ends the span and hands it to the tracer, only the first call counts
+func (span *Span) Finish() {
+	span.lock.Lock()
+	finish := span.finish
+	span.finish = nil
+	if finish != nil {
+		span.End = time.Now()
+	}
+	span.lock.Unlock()
+	if finish != nil {
+		finish(span)
+	}
+}
+
+// Starts the spans of routers and callers. The span is the child of the
+// parent if the parent is valid, and the root of a new trace otherwise
+type Tracer interface {
+	StartSpan(name string, kind SpanKind, parent SpanContext) *Span
+}
+
+// Receives the sampled spans once they are finished
+type SpanExporter interface {
+	ExportSpan(span *Span) error
+}
+
+// A Tracer that samples new traces, follows the sampling decision of remote
+// parents and hands the sampled spans to the exporter
+type ExportingTracer struct {
+	Exporter SpanExporter
+}
+
+func NewTracer(exporter SpanExporter) *ExportingTracer {
+	return &ExportingTracer{Exporter: exporter}
+}
+
+func (tracer *ExportingTracer) StartSpan(name string, kind SpanKind, parent SpanContext) *Span {
+	span := &Span{Name: name, Kind: kind, Start: time.Now()}
+	if parent.Valid() {
+		span.Context = SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled, TraceState: parent.TraceState}
+		span.ParentID = parent.SpanID
+	} else {
+		span.Context = SpanContext{TraceID: newID(16), Sampled: true}
+	}
+	span.Context.SpanID = newID(8)
+	span.finish = func(span *Span) {
+		if span.Context.Sampled {
+			if err := tracer.Exporter.ExportSpan(span); err != nil {
+				DefaultLogger.Warn("Failed to export span", "span", span.Name, "error", err)
+			}
+		}
+	}
+	return span
+}
+
+// Writes spans as JSON, one per line
+type JSONLinesExporter struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+func NewJSONLinesExporter(w io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{writer: w}
+}
+
+// Appends the spans to the file, which gets created if needed
+func NewFileExporter(path string) (*JSONLinesExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLinesExporter(file), nil
+}
+
+func (exporter *JSONLinesExporter) ExportSpan(span *Span) error {
+	span.lock.Lock()
+	line, err := json.Marshal(span)
+	span.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+	_, err = exporter.writer.Write(append(line, '\n'))
+	return err
+}
+
+// Closes the file of exporters made with NewFileExporter
+func (exporter *JSONLinesExporter) Close() error {
+	if closer, ok := exporter.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Records the error of the handler in the span of the request
+func traceError(ctx context.Context, err error) {
+	if span := SpanFrom(ctx); span != nil {
+		span.SetAttribute("error", err.Error())
+	}
+}
+
+// Starts the span of a call, child of the span in the context. The returned
+// context carries the span so that it gets propagated to the callee
+func (caller *Caller) startSpan(ctx context.Context, methodname string) (context.Context, func(code int, err error)) {
+	if caller.Tracer == nil {
+		return ctx, func(int, error) {}
+	}
+
+	var parent SpanContext
+	if span := SpanFrom(ctx); span != nil {
+		parent = span.Context
+	}
+	span := caller.Tracer.StartSpan(methodname, ClientSpan, parent)
+	span.SetAttribute("hermes.sni", caller.callable.SNI())
+	span.SetAttribute("hermes.handler", methodname)
+	return context.WithValue(ctx, spanKey, span), func(code int, err error) {
+		span.SetAttribute("http.status_code", code)
+		if err != nil {
+			span.SetAttribute("error", err.Error())
+		}
+		span.Finish()
+	}
+}
@@ -0,0 +1,199 @@
+package hermes_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryExporter struct {
+	lock  sync.Mutex
+	spans []*hermes.Span
+}
+
+func (exporter *memoryExporter) ExportSpan(span *hermes.Span) error {
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+	exporter.spans = append(exporter.spans, span)
+	return nil
+}
+
+func (exporter *memoryExporter) find(name string, kind hermes.SpanKind) *hermes.Span {
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+	for _, span := range exporter.spans {
+		if span.Name == name && span.Kind == kind {
+			return span
+		}
+	}
+	return nil
+}
+
+type BackendService struct{}
+
+type BackendInput struct {
+	Name string `hermes:"query=name" validate:"required"`
+}
+
+func (_ BackendService) SNI() string { return "backend" }
+
+func (_ BackendService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Lookup", "GET", "/lookup", BackendInput{}, nil),
+	}
+}
+
+func (_ BackendService) Lookup(ctx context.Context, in *BackendInput) error {
+	if in.Name == "missing" {
+		return hermes.NotFound("No %s", in.Name)
+	}
+	return nil
+}
+
+type FrontendService struct {
+	backend *hermes.Caller
+}
+
+func (_ FrontendService) SNI() string { return "frontend" }
+
+func (_ FrontendService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Proxy", "GET", "/proxy", BackendInput{}, nil),
+	}
+}
+
+func (svc FrontendService) Proxy(ctx context.Context, in *BackendInput) error {
+	_, err := svc.backend.Call(ctx, "Lookup", in, nil)
+	return err
+}
+
+func TestTracePropagation(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := hermes.NewTracer(exporter)
+
+	backend := gin.New()
+	backendRouter := hermes.NewRouter(BackendService{})
+	backendRouter.Tracer = tracer
+	require.NoError(t, backendRouter.Serve(backend))
+
+	caller := hermes.NewCaller(BackendService{})
+	caller.Client = &hermes.MockClient{backend}
+	caller.Tracer = tracer
+
+	frontend := gin.New()
+	frontendRouter := hermes.NewRouter(FrontendService{caller})
+	frontendRouter.Tracer = tracer
+	require.NoError(t, frontendRouter.Serve(frontend))
+
+	req := httptest.NewRequest("GET", "/proxy?name=missing", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=value")
+	w := httptest.NewRecorder()
+	frontend.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	server := exporter.find("Proxy", hermes.ServerSpan)
+	client := exporter.find("Lookup", hermes.ClientSpan)
+	remote := exporter.find("Lookup", hermes.ServerSpan)
+	require.NotNil(t, server)
+	require.NotNil(t, client)
+	require.NotNil(t, remote)
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.Context.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", server.ParentID)
+	assert.Equal(t, "vendor=value", remote.Context.TraceState)
+	assert.Equal(t, server.Context.TraceID, client.Context.TraceID)
+	assert.Equal(t, server.Context.SpanID, client.ParentID)
+	assert.Equal(t, client.Context.TraceID, remote.Context.TraceID)
+	assert.Equal(t, client.Context.SpanID, remote.ParentID)
+
+	assert.Equal(t, "frontend", server.Attributes["hermes.sni"])
+	assert.Equal(t, "Proxy", server.Attributes["hermes.handler"])
+	assert.Equal(t, http.StatusNotFound, server.Attributes["http.status_code"])
+	assert.Equal(t, "backend", client.Attributes["hermes.sni"])
+	assert.Equal(t, http.StatusNotFound, client.Attributes["http.status_code"])
+	assert.Contains(t, remote.Attributes["error"], "No missing")
+}
+
+func TestTraceBindingErrors(t *testing.T) {
+	exporter := &memoryExporter{}
+	engine := gin.New()
+	router := hermes.NewRouter(BackendService{})
+	router.Tracer = hermes.NewTracer(exporter)
+	require.NoError(t, router.Serve(engine))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/lookup", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	span := exporter.find("Lookup", hermes.ServerSpan)
+	require.NotNil(t, span)
+	assert.Empty(t, span.ParentID)
+	assert.True(t, span.Context.Valid())
+	assert.Contains(t, span.Attributes["hermes.binding_error"], "Name is required")
+	assert.Equal(t, http.StatusBadRequest, span.Attributes["http.status_code"])
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc := hermes.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "")
+	assert.True(t, sc.Valid())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	assert.False(t, hermes.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "").Sampled)
+	for _, invalid := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		assert.False(t, hermes.ParseTraceparent(invalid, "").Valid(), invalid)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hermes")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.jsonl")
+
+	exporter, err := hermes.NewFileExporter(path)
+	require.NoError(t, err)
+	tracer := hermes.NewTracer(exporter)
+	for _, name := range []string{"first", "second"} {
+		span := tracer.StartSpan(name, hermes.ServerSpan, hermes.SpanContext{})
+		span.SetAttribute("hermes.handler", name)
+		span.Finish()
+		span.Finish()
+	}
+	require.NoError(t, exporter.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	names := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		span := hermes.Span{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		assert.True(t, span.Context.Valid())
+		assert.Equal(t, span.Name, span.Attributes["hermes.handler"])
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"first", "second"}, names)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sync/atomic"
+	"time"
 
 	"github.com/apourchet/hermes/binding"
 	"github.com/gin-gonic/gin"
@@ -22,55 +24,147 @@ func findEndpointByHandler(svc Server, name string) (*Endpoint, error) {
 	return nil, fmt.Errorf("MethodNotFoundError")
 }
 
-func getGinHandler(svc Server, binder binding.Binding, ep *Endpoint, method reflect.Method) gin.HandlerFunc {
+func getGinHandler(router *Router, binder binding.Binding, ep *Endpoint, method reflect.Method, shape handlerShape) gin.HandlerFunc {
+	interceptors := []Interceptor{}
+	interceptors = append(interceptors, router.Interceptors...)
+	interceptors = append(interceptors, ep.Interceptors...)
+	invoke := chainInterceptors(interceptors, methodInvoker(router.server, method, shape))
+
+	timeout := ep.HandlerTimeout
+	if timeout == 0 {
+		timeout = router.DefaultTimeout
+	}
+	if timeout > 0 {
+		invoke = timeoutInvoker(invoke, timeout)
+	}
+
+	bodyLimit := ep.BodyLimit
+	if bodyLimit == 0 {
+		bodyLimit = router.DefaultMaxBodySize
+	}
+
 	return func(ctx *gin.Context) {
 		// Make sure there exists a request id
 		EnsureRequestID(ctx)
+		ctx.Set(startKey, time.Now())
+		ctx.Set(loggerKey, WithFields(router.logger(),
+			"request_id", GetRequestID(ctx),
+			"handler", ep.Handler,
+			"method", ctx.Request.Method,
+			"path", ctx.Request.URL.Path,
+			"remote_addr", ctx.Request.RemoteAddr,
+		))
+		ctx.Set(serverKey, router.server)
+		ctx.Set(drainingKey, atomic.LoadInt32(&router.draining) == 1)
+		ctx.Set(readinessKey, router.ReadinessChecks)
+		ctx.Set(metricsKey, router.Metrics)
+		if router.Metrics != nil {
+			// Deferred first so that the status written on panics is recorded
+			done := router.Metrics.serving(serverSNI(router.server), ep)
+			defer func() { done(ctx.Writer.Status()) }()
+		}
+		if router.Tracer != nil {
+			span := router.Tracer.StartSpan(ep.Handler, ServerSpan, extractSpanContext(ctx.Request.Header))
+			span.SetAttribute("hermes.sni", serverSNI(router.server))
+			span.SetAttribute("hermes.handler", ep.Handler)
+			span.SetAttribute("http.method", ctx.Request.Method)
+			span.SetAttribute("http.path", ctx.Request.URL.Path)
+			ctx.Set(spanKey, span)
+			defer func() {
+				span.SetAttribute("http.status_code", ctx.Writer.Status())
+				span.Finish()
+			}()
+		}
+		defer recoverPanic(router, ctx, ep)
 
-		// Prepare inputs and outputs
-		var input reflect.Value
-		if ep.InputType != nil {
-			input = reflect.New(ep.InputType)
+		// Let browsers read the response, errors included
+		if router.CORS != nil {
+			if origin := router.CORS.origin(ctx); origin != "" {
+				router.CORS.setOriginHeaders(ctx, origin)
+			} else {
+				router.CORS.setDisallowedHeaders(ctx)
+			}
 		}
 
-		var output reflect.Value
-		if ep.OutputType != nil {
-			output = reflect.New(ep.OutputType)
+		if err := limitBody(ctx.Request, bodyLimit); err != nil {
+			code, e := ToError(http.StatusRequestEntityTooLarge, err)
+			DefaultErrorHandler(ctx, ctx.Request.URL.Path, code, err)
+			writeError(ctx, code, e)
+			return
 		}
 
-		// Bind input to context
-		if input.IsValid() {
-			err := binder.Bind(ctx, input.Interface())
+		// Authenticate the request unless the endpoint is public
+		if !ep.AllowAnonymous {
+			principal, err := router.authenticate(ctx.Request)
 			if err != nil {
-				ctx.JSON(http.StatusBadRequest, &Error{err.Error()})
+				code, e := ToError(http.StatusUnauthorized, err)
+				DefaultErrorHandler(ctx, ctx.Request.URL.Path, code, err)
+				writeError(ctx, code, e)
 				return
+			} else if principal != nil {
+				ctx.Set(principalKey, principal)
 			}
 		}
 
-		// Prepare arguments to function
-		args := []reflect.Value{reflect.ValueOf(svc), reflect.ValueOf(ctx)}
-		if input.IsValid() {
-			args = append(args, input)
+		// Prepare inputs and outputs
+		inv := &Invocation{Context: ctx, Gin: ctx, Endpoint: ep}
+		if ep.InputType != nil {
+			inv.Input = reflect.New(ep.InputType).Interface()
+		}
+
+		var stream *streamWriter
+		if ep.Streaming != "" {
+			stream = newStreamWriter(ctx, ep.Streaming)
+			inv.Output = stream.sender(ep.OutputType)
+		} else if ep.OutputType != nil {
+			inv.Output = reflect.New(ep.OutputType).Interface()
 		}
-		if output.IsValid() {
-			args = append(args, output)
+
+		// Bind input to context
+		if inv.Input != nil {
+			err := prepareBody(ctx.Request, bodyLimit, router.JSONLimits)
+			if err == nil {
+				err = binder.Bind(ctx, inv.Input)
+			}
+			if err != nil {
+				if router.Metrics != nil {
+					router.Metrics.Add("hermes_binding_failures_total", metricLabels("sni", serverSNI(router.server), "handler", ep.Handler), 1)
+				}
+				if span := SpanFrom(ctx); span != nil {
+					span.SetAttribute("hermes.binding_error", err.Error())
+				}
+				code, e := ToError(http.StatusBadRequest, err)
+				DefaultErrorHandler(ctx, ctx.Request.URL.Path, code, err)
+				writeError(ctx, code, e)
+				return
+			}
 		}
 
-		// Call function
-		vals := method.Func.Call(args)
-		code := int(vals[0].Int())
+		// Call function through the interceptors
+		code, err := invoke(inv)
 		if code == HERMES_CODE_BYPASS {
 			// Bypass code, do nothing here
 			return
 		}
 
-		if !vals[1].IsNil() { // If there was an error
-			errVal := vals[1].Interface().(error)
-			DefaultErrorHandler(ctx, ctx.Request.URL.Path, code, errVal)
-			ctx.JSON(code, &Error{errVal.Error()})
-		} else if output.IsValid() {
+		if stream != nil {
+			var e *Error
+			if err != nil {
+				code, e = ToError(code, err)
+				traceError(ctx, err)
+				DefaultErrorHandler(ctx, ctx.Request.URL.Path, code, err)
+			} else {
+				DefaultSuccessHandler(ctx, ctx.Request.URL.Path, code)
+			}
+			stream.finish(code, e)
+		} else if err != nil { // If there was an error
+			code, e := ToError(code, err)
+			traceError(ctx, err)
+			DefaultErrorHandler(ctx, ctx.Request.URL.Path, code, err)
+			writeError(ctx, code, e)
+		} else if inv.Output != nil {
 			DefaultSuccessHandler(ctx, ctx.Request.URL.Path, code)
-			ctx.JSON(code, output.Interface())
+			writeOutput(ctx, router.Encoders, router.CompressionThreshold, code, inv.Output)
 		} else {
 			DefaultSuccessHandler(ctx, ctx.Request.URL.Path, code)
 			ctx.Writer.WriteHeader(code)
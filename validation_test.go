@@ -0,0 +1,167 @@
+package hermes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apourchet/hermes"
+	"github.com/apourchet/hermes/binding"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ValidatedService struct {
+	hermes.HealthChecker
+}
+
+type Order struct {
+	Item     string `json:"item" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1,max=100"`
+}
+
+func (_ ValidatedService) SNI() string { return "UNUSED" }
+
+func (_ ValidatedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Order", "POST", "/orders", Order{}, nil),
+		hermes.EP("AuditedOrder", "POST", "/audited", AuditedOrder{}, nil),
+		hermes.EP("Transfer", "POST", "/transfers", Transfer{}, nil),
+	}
+}
+
+type Transfer struct {
+	From, To string
+}
+
+func (t Transfer) Validate() error {
+	if t.From == t.To {
+		return fmt.Errorf("Cannot transfer to the same account")
+	}
+	return nil
+}
+
+func (_ ValidatedService) Transfer(ctx context.Context, in *Transfer) error {
+	return nil
+}
+
+type Audit struct {
+	Author  string
+	Comment string
+}
+
+type AuditedOrder struct {
+	Audit
+	Item string `json:"item" validate:"required"`
+}
+
+func (_ ValidatedService) AuditedOrder(ctx context.Context, in *AuditedOrder) error {
+	return nil
+}
+
+func (_ ValidatedService) Order(ctx context.Context, in *Order) (int, error) {
+	return http.StatusCreated, nil
+}
+
+func TestValidation(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(ValidatedService{}).Serve(engine))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/orders", bytes.NewBufferString(`{"quantity": 500}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	out := hermes.Error{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	assert.Equal(t, []binding.FieldError{
+		{Field: "item", Rule: "required", Message: "is required"},
+		{Field: "quantity", Rule: "max", Message: "must be at most 100"},
+	}, out.Fields)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/orders", bytes.NewBufferString(`{"item": "book", "quantity": 2}`)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestCallerValidation(t *testing.T) {
+	caller := hermes.NewCaller(ValidatedService{})
+	caller.Client = &hermes.MockClient{gin.New()}
+
+	// Invalid inputs are not sent
+	code, err := caller.Call(context.Background(), "Order", &Order{Quantity: 1}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.IsType(t, &binding.ValidationError{}, err)
+}
+
+func TestValidatableInput(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(ValidatedService{}).Serve(engine))
+	caller := hermes.NewCaller(ValidatedService{})
+	caller.Client = &hermes.MockClient{engine}
+
+	// Callers reject the input like the server does
+	code, err := caller.Call(context.Background(), "Transfer", &Transfer{"a", "a"}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.EqualError(t, err, "Cannot transfer to the same account")
+	require.IsType(t, &hermes.Error{}, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*hermes.Error).Status)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/transfers", bytes.NewBufferString(`{"From": "a", "To": "a"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	code, err = caller.Call(context.Background(), "Transfer", &Transfer{"a", "b"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+}
+
+type MisvalidatedService struct{}
+
+type BadOrder struct {
+	Quantity int `validate:"min=zero"`
+	Lines    []BadLine
+}
+
+type BadLine struct {
+	Price int `validate:"positive"`
+}
+
+func (_ MisvalidatedService) SNI() string { return "UNUSED" }
+
+func (_ MisvalidatedService) Endpoints() hermes.EndpointMap {
+	return hermes.EndpointMap{
+		hermes.EP("Order", "POST", "/orders", BadOrder{}, nil),
+	}
+}
+
+func (_ MisvalidatedService) Order(ctx context.Context, in *BadOrder) (int, error) {
+	return http.StatusCreated, nil
+}
+
+func TestInvalidValidationTags(t *testing.T) {
+	err := hermes.NewRouter(MisvalidatedService{}).Serve(gin.New())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid validation rule min=zero")
+	assert.Contains(t, err.Error(), "Field Price of hermes_test.BadLine: invalid validation rule positive")
+}
+
+func TestEmbeddedInputValidation(t *testing.T) {
+	engine := gin.New()
+	require.NoError(t, hermes.NewRouter(ValidatedService{}).Serve(engine))
+	caller := hermes.NewCaller(ValidatedService{})
+	caller.Client = &hermes.MockClient{engine}
+
+	code, err := caller.Call(context.Background(), "AuditedOrder", &AuditedOrder{Audit{"jo", "ok"}, "book"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/audited", bytes.NewBufferString(`{"Author": "jo"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"item"`)
+}